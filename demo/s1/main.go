@@ -32,18 +32,15 @@ func main() {
 	etcdCfg.Timeout = 3
 
 	baseCfg.Etcd = etcdCfg
-	// 设置Middleware
-	frame.Prepare(preprareMiddleware, afterMiddleware)
+	// 设置拦截器
+	frame.Use(frame.Recovery(), frame.Logging(), frame.Metrics(), contentTypeInterceptor)
 
 	s := new(Hello)
 	// 直接注册就行了
 	pb.RegisterHelloServer(s, &baseCfg)
 }
 
-func preprareMiddleware(c frame.Context) error {
+func contentTypeInterceptor(c frame.Context, next frame.Next) error {
 	c.Response().SetHeader("Content-Type", "application/json;utf8")
-	return nil
-}
-func afterMiddleware(c frame.Context) error {
-	return nil
+	return next(c)
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/kwins/iceberg/frame"
 	"github.com/kwins/iceberg/frame/config"
 	"github.com/kwins/iceberg/frame/protocol"
+	"github.com/kwins/iceberg/frame/validate"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -75,6 +76,32 @@ func init() {
 	proto.RegisterType((*HelloResponse)(nil), "hello.HelloResponse")
 }
 
+// Validate 校验HelloRequest的字段
+func (m *HelloRequest) Validate() error {
+	if m == nil {
+		return nil
+	}
+	var errs validate.Errors
+	if m.Name == "" {
+		errs.Add("name", "required", "不能为空")
+	}
+	if m.Name != "" {
+		if validate.RuneLen(m.Name) > 64 {
+			errs.Add("name", "max_len", "长度不能大于64")
+		}
+	}
+	return errs.Err()
+}
+
+// Validate 校验HelloResponse的字段
+func (m *HelloResponse) Validate() error {
+	if m == nil {
+		return nil
+	}
+	var errs validate.Errors
+	return errs.Err()
+}
+
 // Reference imports to suppress errors if they are not otherwise used.
 var _ context.Context
 
@@ -91,7 +118,7 @@ func SayHello(ctx frame.Context, in *HelloRequest, opts ...frame.CallOption) (*H
 	if err != nil {
 		return nil, err
 	}
-	back, err := frame.DeliverTo(task)
+	back, err := frame.DeliverTo(task, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -109,7 +136,7 @@ func GetExample(ctx frame.Context, in *HelloRequest, opts ...frame.CallOption) (
 	if err != nil {
 		return nil, err
 	}
-	back, err := frame.DeliverTo(task)
+	back, err := frame.DeliverTo(task, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +153,7 @@ func PostExample(ctx frame.Context, in *HelloRequest, opts ...frame.CallOption)
 	if err != nil {
 		return nil, err
 	}
-	back, err := frame.DeliverTo(task)
+	back, err := frame.DeliverTo(task, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +170,7 @@ func PostFormExample(ctx frame.Context, in *HelloRequest, opts ...frame.CallOpti
 	if err != nil {
 		return nil, err
 	}
-	back, err := frame.DeliverTo(task)
+	back, err := frame.DeliverTo(task, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -160,7 +187,7 @@ func Timeout(ctx frame.Context, in *HelloRequest, opts ...frame.CallOption) (*He
 	if err != nil {
 		return nil, err
 	}
-	back, err := frame.DeliverTo(task)
+	back, err := frame.DeliverTo(task, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -91,7 +91,7 @@ func SayHi(ctx frame.Context, in *HiRequest, opts ...frame.CallOption) (*HiRespo
 	if err != nil {
 		return nil, err
 	}
-	back, err := frame.DeliverTo(task)
+	back, err := frame.DeliverTo(task, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,269 @@
+package frame
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	log "github.com/kwins/iceberg/frame/icelog"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 访问日志
+// 每个请求输出一条结构化日志，字段可配置;成功的请求按采样率输出，失败的请求(status>=400)全部输出;
+// header，form和body中需要脱敏的key输出为***，body超过MaxBody时截断;
+// 错误码不是http状态码时status为500，原始错误码输出在errcode字段;
+// client_ip只在对端是可信代理(metadataCfg.trusted_proxies)时才使用X-Forwarded-For和X-Real-IP
+
+const redacted = "***"
+
+var (
+	defaultAccessFields = []string{"method", "uri", "status", "latency",
+		"req_size", "resp_size", "bizid", "client_ip"}
+	defaultRedactKeys = []string{"Authorization", "Cookie", "Set-Cookie", "password", "token"}
+)
+
+// AccessLog 访问日志
+type AccessLog struct {
+	cfg     config.AccessLogCfg
+	redact  map[string]struct{}
+	trusted []*net.IPNet // 可信代理
+	logger  *log.Logger
+}
+
+// NewAccessLog new access log
+func NewAccessLog(cfg config.AccessLogCfg) *AccessLog {
+	if len(cfg.Fields) == 0 {
+		cfg.Fields = defaultAccessFields
+	}
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = 1
+	}
+	if len(cfg.Redact) == 0 {
+		cfg.Redact = defaultRedactKeys
+	}
+	al := &AccessLog{cfg: cfg, redact: make(map[string]struct{}, len(cfg.Redact))}
+	for _, k := range cfg.Redact {
+		al.redact[strings.ToLower(k)] = struct{}{}
+	}
+	al.logger = log.With(log.String("type", "access")).WithCallerSkip(1)
+	return al
+}
+
+// SetTrustedProxies 设置可信代理的IP或CIDR，Start时调用
+func (al *AccessLog) SetTrustedProxies(addrs []string) {
+	al.trusted = nil
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+				addr += "/32"
+			} else {
+				addr += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(addr)
+		if err != nil {
+			log.Errorf("iceberg:bad trusted proxy %s,%s", addr, err.Error())
+			continue
+		}
+		al.trusted = append(al.trusted, n)
+	}
+}
+
+// Log 输出一条访问日志
+// req,resp 请求和响应，resp可以为空; status 响应状态码，0表示按resp中的错误信息计算
+func (al *AccessLog) Log(req, resp *protocol.Proto, status int, cost time.Duration) {
+	if al == nil || al.cfg.Disable {
+		return
+	}
+	if status == 0 {
+		status = respStatus(resp)
+	}
+	if status < http.StatusBadRequest && al.cfg.SampleRate < 1 && rand.Float64() >= al.cfg.SampleRate {
+		return
+	}
+
+	var kv = make([]interface{}, 0, len(al.cfg.Fields)*2)
+	for _, name := range al.cfg.Fields {
+		var v interface{}
+		switch name {
+		case "method":
+			v = req.GetMethod().String()
+		case "uri":
+			v = req.GetServeURI()
+		case "serve_method":
+			v = req.GetServeMethod()
+		case "status":
+			if code := errCode(resp); code != 0 && code != status {
+				kv = append(kv, "errcode", code)
+			}
+			v = status
+		case "latency":
+			v = cost.String()
+		case "req_size":
+			v = len(req.GetBody())
+		case "resp_size":
+			v = len(resp.GetBody())
+		case "bizid":
+			v = req.GetBizid()
+		case "request_id":
+			v = req.GetRequestID()
+		case "client_ip":
+			v = al.realIP(req)
+		case "header":
+			v = al.redactMap(req.GetHeader())
+		case "form":
+			v = al.redactMap(req.GetForm())
+		case "req_body":
+			v = al.body(req.GetBody())
+		case "resp_body":
+			v = al.body(resp.GetBody())
+		case "error":
+			v = string(resp.GetErr())
+		default:
+			continue
+		}
+		kv = append(kv, name, v)
+	}
+	if status >= http.StatusInternalServerError {
+		al.logger.Errorw("access", kv...)
+	} else if status >= http.StatusBadRequest {
+		al.logger.Warnw("access", kv...)
+	} else {
+		al.logger.Infow("access", kv...)
+	}
+}
+
+func (al *AccessLog) redactMap(m map[string]string) map[string]string {
+	var r = make(map[string]string, len(m))
+	for k, v := range m {
+		if _, ok := al.redact[strings.ToLower(k)]; ok {
+			v = redacted
+		}
+		r[k] = v
+	}
+	return r
+}
+
+// body 脱敏并截断body，MaxBody为0时不输出
+func (al *AccessLog) body(b []byte) string {
+	if al.cfg.MaxBody <= 0 || len(b) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err == nil {
+		if rb, err := json.Marshal(al.redactValue(v)); err == nil {
+			b = rb
+		}
+	}
+	if len(b) > al.cfg.MaxBody {
+		return string(b[:al.cfg.MaxBody]) + "...(" + strconv.Itoa(len(b)) + " bytes)"
+	}
+	return string(b)
+}
+
+func (al *AccessLog) redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if _, ok := al.redact[strings.ToLower(k)]; ok {
+				t[k] = redacted
+			} else {
+				t[k] = al.redactValue(e)
+			}
+		}
+	case []interface{}:
+		for i := range t {
+			t[i] = al.redactValue(t[i])
+		}
+	}
+	return v
+}
+
+// respStatus 根据响应中的错误信息计算状态码，错误码为4xx,5xx时使用错误码，否则为500
+func respStatus(resp *protocol.Proto) int {
+	if resp == nil {
+		return http.StatusInternalServerError
+	}
+	if len(resp.GetErr()) == 0 {
+		if resp.GetStatus() != 0 {
+			return int(resp.GetStatus())
+		}
+		return http.StatusOK
+	}
+	if code := errCode(resp); code >= http.StatusBadRequest && code < 600 {
+		return code
+	}
+	return http.StatusInternalServerError
+}
+
+// errCode 响应中的原始错误码，没有错误时为0
+func errCode(resp *protocol.Proto) int {
+	if len(resp.GetErr()) == 0 {
+		return 0
+	}
+	var info protocol.ErrInfo
+	if err := json.Unmarshal(resp.GetErr(), &info); err != nil {
+		return 0
+	}
+	return info.ErrCode
+}
+
+// realIP 请求的客户端IP
+// 对端是可信代理时，从右向左取X-Forwarded-For中第一个不可信的地址，没有X-Forwarded-For时使用X-Real-IP;
+// 否则使用对端地址
+func (al *AccessLog) realIP(req *protocol.Proto) string {
+	ra, _, err := net.SplitHostPort(req.GetRemoteAddr())
+	if err != nil {
+		ra = req.GetRemoteAddr()
+	}
+	if !al.isTrusted(ra) {
+		return ra
+	}
+	header := req.GetHeader()
+	if xff := headerValue(header, protocol.HeaderXForwardedFor); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if i == 0 || !al.isTrusted(ip) {
+				return ip
+			}
+		}
+	}
+	if ip := headerValue(header, protocol.HeaderXRealIP); ip != "" {
+		return ip
+	}
+	return ra
+}
+
+// isTrusted 地址是否为可信代理
+func (al *AccessLog) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range al.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerValue 获取header，兼容规范化前后的key
+func headerValue(header map[string]string, key string) string {
+	if v, ok := header[key]; ok {
+		return v
+	}
+	return header[http.CanonicalHeaderKey(key)]
+}
+
+// LogAccess 使用服务的访问日志配置输出一条访问日志
+func LogAccess(req, resp *protocol.Proto, status int, cost time.Duration) {
+	Instance().access.Log(req, resp, status, cost)
+}
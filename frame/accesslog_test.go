@@ -0,0 +1,67 @@
+package frame
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kwins/iceberg/frame/config"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+func TestAccessLogRedact(t *testing.T) {
+	al := NewAccessLog(config.AccessLogCfg{MaxBody: 40})
+	h := al.redactMap(map[string]string{"Authorization": "Bearer abc", "Accept": "*/*"})
+	if h["Authorization"] != redacted || h["Accept"] != "*/*" {
+		t.Errorf("redact header fail,%v", h)
+	}
+
+	body := al.body([]byte(`{"name":"kwins","Password":"123456","list":[{"token":"t"}]}`))
+	if strings.Contains(body, "123456") || strings.Contains(body, `"t"`) {
+		t.Errorf("redact body fail,%s", body)
+	}
+	if !strings.HasSuffix(body, "bytes)") {
+		t.Errorf("body should be truncated,%s", body)
+	}
+
+	if b := NewAccessLog(config.AccessLogCfg{}).body([]byte("abc")); b != "" {
+		t.Errorf("body should not be logged by default,%s", b)
+	}
+}
+
+func TestAccessLogStatus(t *testing.T) {
+	var resp protocol.Proto
+	if s := respStatus(&resp); s != http.StatusOK {
+		t.Errorf("status got %d", s)
+	}
+	resp.FillErrInfo(http.StatusNotFound, ErrMethodNotFound)
+	if s := respStatus(&resp); s != http.StatusNotFound {
+		t.Errorf("status got %d", s)
+	}
+	if s := respStatus(nil); s != http.StatusInternalServerError {
+		t.Errorf("status got %d", s)
+	}
+	resp.FillErrInfo(-1002, ErrMethodNotFound)
+	if s, code := respStatus(&resp), errCode(&resp); s != http.StatusInternalServerError || code != -1002 {
+		t.Errorf("status got %d,errcode %d", s, code)
+	}
+
+	al := NewAccessLog(config.AccessLogCfg{})
+	req := protocol.Proto{RemoteAddr: "10.0.0.1:5678", Header: map[string]string{}}
+	if ip := al.realIP(&req); ip != "10.0.0.1" {
+		t.Errorf("real ip got %s", ip)
+	}
+	// 不可信的对端伪造X-Forwarded-For
+	req.Header["X-Forwarded-For"] = "1.1.1.1, 10.0.0.2"
+	if ip := al.realIP(&req); ip != "10.0.0.1" {
+		t.Errorf("real ip got %s", ip)
+	}
+	al.SetTrustedProxies([]string{"10.0.0.0/24"})
+	if ip := al.realIP(&req); ip != "1.1.1.1" {
+		t.Errorf("real ip got %s", ip)
+	}
+	req.Header["X-Forwarded-For"] = "6.6.6.6, 1.1.1.1, 10.0.0.2"
+	if ip := al.realIP(&req); ip != "1.1.1.1" {
+		t.Errorf("real ip got %s", ip)
+	}
+}
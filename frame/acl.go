@@ -0,0 +1,261 @@
+package frame
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	log "github.com/kwins/iceberg/frame/icelog"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 服务间调用的访问控制
+// 调用方发出的请求Header中带有X-Iceberg-Caller(自己的服务名称)，配置了aclCfg.secret时还带有签名时间和
+// HMAC-SHA256签名，签名内容为 调用方,时间,RequestID,ServeURI,ServeMethod,Bizid和Body,Form,上传文件的摘要;
+// 服务端签名校验失败，时间偏差过大或签名在时间偏差内已使用过(重放)时视为匿名调用方;
+// 策略保存在etcd中 [服务URI]/provider/acl(服务的所有方法) 和 [服务URI]/[方法]/provider/acl，值为JSON:
+//	{"allow":["Gateway","Order"],"deny":["Report"]}
+// 方法的策略优先于服务的策略;Deny中的调用方总是拒绝，Allow不为空时只允许其中的调用方，* 表示所有通过校验的调用方;
+// 没有策略或Allow为空时，default_deny为true则拒绝，否则允许;
+// 被拒绝的调用返回403，并输出审计日志(名称为frame.acl);
+// aclCfg.policy_file 不为空时从本地文件加载策略，key为方法名称，* 表示服务的策略:
+//	{"*":{"allow":["Gateway"]},"create":{"allow":["Order"]}}
+
+const (
+	aclAnyCaller      = "*"
+	defaultACLMaxSkew = 300
+)
+
+// ACLPolicy 服务或方法的访问控制策略
+type ACLPolicy struct {
+	Allow []string `json:"allow"` // 允许的调用方，* 表示所有通过校验的调用方
+	Deny  []string `json:"deny"`  // 拒绝的调用方，优先于Allow
+}
+
+// ParseACLPolicy 解析访问控制策略
+func ParseACLPolicy(value []byte) (*ACLPolicy, error) {
+	var policy ACLPolicy
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Permit 是否允许调用方访问，caller为空表示匿名调用方，policy为nil时按defaultDeny决定
+func (policy *ACLPolicy) Permit(caller string, defaultDeny bool) bool {
+	if policy == nil {
+		return !defaultDeny
+	}
+	for _, d := range policy.Deny {
+		if matchCaller(d, caller) {
+			return false
+		}
+	}
+	if len(policy.Allow) == 0 {
+		return !defaultDeny
+	}
+	for _, a := range policy.Allow {
+		if matchCaller(a, caller) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCaller(pattern, caller string) bool {
+	if caller == "" {
+		return false
+	}
+	return pattern == aclAnyCaller || strings.EqualFold(pattern, caller)
+}
+
+// SetACL 设置访问控制配置，Start时调用;测试中可以直接调用以加载本地策略文件
+func (discover *Discover) SetACL(cfg config.ACLCfg) error {
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = defaultACLMaxSkew
+	}
+	var policies map[string]*ACLPolicy
+	if cfg.PolicyFile != "" {
+		b, err := ioutil.ReadFile(cfg.PolicyFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &policies); err != nil {
+			return fmt.Errorf("bad acl policy file %s,%s", cfg.PolicyFile, err.Error())
+		}
+	}
+	discover.aclLocker.Lock()
+	discover.acl = cfg
+	discover.policies = policies
+	discover.aclLocker.Unlock()
+	return nil
+}
+
+// setPolicy 同步etcd中本服务或本服务方法的策略，value为空时删除;使用本地策略文件时忽略
+func (discover *Discover) setPolicy(URI, value string) {
+	var name string
+	if discover.isSelf(URI) {
+		name = aclAnyCaller
+	} else if i := strings.LastIndexByte(URI, '/'); i != -1 && discover.isSelf(URI[:i]) {
+		name = URI[i+1:]
+	} else {
+		return
+	}
+
+	var policy *ACLPolicy
+	if value != "" {
+		var err error
+		if policy, err = ParseACLPolicy([]byte(value)); err != nil {
+			log.Errorf("iceberg:bad acl policy of %s,detail=%s", URI, err.Error())
+			return
+		}
+	}
+
+	discover.aclLocker.Lock()
+	defer discover.aclLocker.Unlock()
+	if discover.acl.PolicyFile != "" {
+		return
+	}
+	if policy == nil {
+		delete(discover.policies, name)
+		log.Infof("iceberg:acl policy of %s removed", URI)
+		return
+	}
+	if discover.policies == nil {
+		discover.policies = make(map[string]*ACLPolicy)
+	}
+	discover.policies[name] = policy
+	log.Infof("iceberg:acl policy of %s changed:%s", URI, value)
+}
+
+// signCaller 在发往下游的请求中带上本服务名称和签名，覆盖请求中已有的值
+func (discover *Discover) signCaller(task *protocol.Proto) {
+	task.DelHeader(protocol.HeaderXIcebergCaller)
+	task.DelHeader(protocol.HeaderXIcebergCallerTime)
+	task.DelHeader(protocol.HeaderXIcebergCallerSign)
+	if discover.name == "" {
+		return
+	}
+	task.SetHeader(protocol.HeaderXIcebergCaller, discover.name)
+
+	discover.aclLocker.RLock()
+	secret := discover.acl.Secret
+	discover.aclLocker.RUnlock()
+	if secret == "" {
+		return
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	task.SetHeader(protocol.HeaderXIcebergCallerTime, ts)
+	task.SetHeader(protocol.HeaderXIcebergCallerSign, callerSign(secret, discover.name, ts, task))
+}
+
+// verifyCaller 校验请求中的调用方，返回通过校验的调用方服务名称，没有通过时返回空
+func (discover *Discover) verifyCaller(r *protocol.Proto) string {
+	header := r.GetHeader()
+	caller := headerValue(header, protocol.HeaderXIcebergCaller)
+	if caller == "" {
+		return ""
+	}
+
+	discover.aclLocker.RLock()
+	secret, skew := discover.acl.Secret, int64(discover.acl.MaxSkew)
+	discover.aclLocker.RUnlock()
+	if secret == "" {
+		return caller
+	}
+	ts := headerValue(header, protocol.HeaderXIcebergCallerTime)
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ""
+	}
+	if d := time.Now().Unix() - n; d > skew || d < -skew {
+		return ""
+	}
+	sign := headerValue(header, protocol.HeaderXIcebergCallerSign)
+	if !hmac.Equal([]byte(sign), []byte(callerSign(secret, caller, ts, r))) {
+		return ""
+	}
+	if !discover.firstSign(sign, n+skew) {
+		log.Named("frame.acl").Warnw("replayed caller sign",
+			"caller", caller, "uri", r.GetServeURI(), "method", r.GetServeMethod(), "bizid", r.GetBizid())
+		return ""
+	}
+	return caller
+}
+
+// firstSign 记录签名，签名在过期前已记录过时返回false;expire 过期时间，unix秒
+func (discover *Discover) firstSign(sign string, expire int64) bool {
+	now := time.Now().Unix()
+	discover.signLocker.Lock()
+	defer discover.signLocker.Unlock()
+	if discover.signs == nil {
+		discover.signs = make(map[string]int64)
+	}
+	// 每秒最多清理一次过期的签名
+	if now != discover.signPruned {
+		discover.signPruned = now
+		for k, e := range discover.signs {
+			if e < now {
+				delete(discover.signs, k)
+			}
+		}
+	}
+	if e, ok := discover.signs[sign]; ok && e >= now {
+		return false
+	}
+	discover.signs[sign] = expire
+	return true
+}
+
+// authorize 校验调用方并按策略检查，返回通过校验的调用方;拒绝时输出审计日志并返回ErrAccessDenied
+// peer 连接的对端地址
+func (discover *Discover) authorize(r *protocol.Proto, peer string) (string, error) {
+	caller := discover.verifyCaller(r)
+
+	discover.aclLocker.RLock()
+	enable, defaultDeny := discover.acl.Enable, discover.acl.DefaultDeny
+	policy := discover.policies[r.GetServeMethod()]
+	if policy == nil {
+		policy = discover.policies[aclAnyCaller]
+	}
+	discover.aclLocker.RUnlock()
+
+	if !enable || policy.Permit(caller, defaultDeny) {
+		return caller, nil
+	}
+	log.Named("frame.acl").Warnw("acl denied",
+		"caller", caller,
+		"claimed", headerValue(r.GetHeader(), protocol.HeaderXIcebergCaller),
+		"peer", peer,
+		"uri", r.GetServeURI(),
+		"method", r.GetServeMethod(),
+		"bizid", r.GetBizid())
+	return caller, ErrAccessDenied
+}
+
+// callerSign 调用方签名
+func callerSign(secret, caller, ts string, task *protocol.Proto) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{caller, ts, strconv.FormatInt(task.GetRequestID(), 10),
+		task.GetServeURI(), task.GetServeMethod(), task.GetBizid(), contentDigest(task)}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// contentDigest 请求Body，Form和上传文件的摘要
+func contentDigest(task *protocol.Proto) string {
+	h := sha256.New()
+	h.Write(task.GetBody())
+	h.Write([]byte("\n" + task.URLValues().Encode()))
+	for _, f := range task.GetFiles() {
+		fmt.Fprintf(h, "\n%s\n%s\n", f.GetField(), f.GetFilename())
+		h.Write(f.GetContent())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
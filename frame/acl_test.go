@@ -0,0 +1,150 @@
+package frame
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+func TestACLPolicyPermit(t *testing.T) {
+	policy := &ACLPolicy{Allow: []string{"Gateway", "*"}, Deny: []string{"report"}}
+	var cases = []struct {
+		policy      *ACLPolicy
+		caller      string
+		defaultDeny bool
+		want        bool
+	}{
+		{nil, "", false, true},
+		{nil, "Order", true, false},
+		{policy, "Order", true, true},
+		{policy, "Report", false, false},
+		{policy, "", false, false},
+		{&ACLPolicy{Allow: []string{"Gateway"}}, "Order", false, false},
+		{&ACLPolicy{Deny: []string{"Report"}}, "Order", false, true},
+		{&ACLPolicy{Deny: []string{"Report"}}, "Order", true, false},
+	}
+	for i, c := range cases {
+		if got := c.policy.Permit(c.caller, c.defaultDeny); got != c.want {
+			t.Errorf("case %d got %v want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestCallerSign(t *testing.T) {
+	client := &Discover{name: "Order"}
+	client.SetACL(config.ACLCfg{Secret: "s3cret"})
+	server := &Discover{}
+	server.SetACL(config.ACLCfg{Secret: "s3cret"})
+
+	task := &protocol.Proto{Bizid: "b1", RequestID: 1, ServeURI: "/services/v1/pay", ServeMethod: "refund",
+		Body: []byte(`{"amount":1}`)}
+	task.SetHeader(protocol.HeaderXIcebergCaller, "Gateway")
+	client.signCaller(task)
+	if caller := server.verifyCaller(task); caller != "Order" {
+		t.Errorf("verify fail,got %q", caller)
+	}
+	// 重放
+	if caller := server.verifyCaller(task); caller != "" {
+		t.Errorf("replayed request should fail,got %q", caller)
+	}
+
+	// 篡改请求或伪造调用方
+	forged := *task
+	forged.ServeMethod = "pay"
+	if caller := server.verifyCaller(&forged); caller != "" {
+		t.Errorf("tampered request should fail,got %q", caller)
+	}
+	forged = *task
+	forged.Body = []byte(`{"amount":10000}`)
+	if caller := server.verifyCaller(&forged); caller != "" {
+		t.Errorf("tampered body should fail,got %q", caller)
+	}
+	forged = *task
+	forged.Form = map[string]string{"amount": "10000"}
+	if caller := server.verifyCaller(&forged); caller != "" {
+		t.Errorf("tampered form should fail,got %q", caller)
+	}
+	forged = *task
+	forged.RequestID++
+	if caller := server.verifyCaller(&forged); caller != "" {
+		t.Errorf("tampered request id should fail,got %q", caller)
+	}
+	forged = *task
+	forged.Header = map[string]string{protocol.HeaderXIcebergCaller: "Gateway"}
+	if caller := server.verifyCaller(&forged); caller != "" {
+		t.Errorf("unsigned caller should fail,got %q", caller)
+	}
+
+	// 签名过期
+	ts := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	task.SetHeader(protocol.HeaderXIcebergCallerTime, ts)
+	task.SetHeader(protocol.HeaderXIcebergCallerSign, callerSign("s3cret", "Order", ts, task))
+	if caller := server.verifyCaller(task); caller != "" {
+		t.Errorf("expired sign should fail,got %q", caller)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "acl.json")
+	policies := `{"*":{"allow":["Gateway"]},"refund":{"allow":["Order"]}}`
+	if err := ioutil.WriteFile(file, []byte(policies), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	discover := &Discover{selfURI: []string{"/services/v1/pay"}}
+	if err := discover.SetACL(config.ACLCfg{Enable: true, DefaultDeny: true, PolicyFile: file}); err != nil {
+		t.Fatal(err)
+	}
+	call := func(caller, method string) error {
+		task := &protocol.Proto{ServeURI: "/services/v1/pay", ServeMethod: method}
+		task.SetHeader(protocol.HeaderXIcebergCaller, caller)
+		got, err := discover.authorize(task, "127.0.0.1:5000")
+		if err == nil && got != caller {
+			t.Errorf("caller want %s,got %s", caller, got)
+		}
+		return err
+	}
+	if err := call("Order", "refund"); err != nil {
+		t.Errorf("method policy should allow,%v", err)
+	}
+	if err := call("Gateway", "refund"); err != ErrAccessDenied {
+		t.Errorf("method policy should deny,%v", err)
+	}
+	if err := call("Gateway", "pay"); err != nil {
+		t.Errorf("service policy should allow,%v", err)
+	}
+	if err := call("", "pay"); err != ErrAccessDenied {
+		t.Errorf("anonymous should deny,%v", err)
+	}
+
+	// 使用本地策略文件时忽略etcd中的策略
+	discover.setPolicy("/services/v1/pay/refund", `{"allow":["Gateway"]}`)
+	if err := call("Gateway", "refund"); err != ErrAccessDenied {
+		t.Errorf("etcd policy should be ignored,%v", err)
+	}
+
+	discover.SetACL(config.ACLCfg{Enable: true, DefaultDeny: true})
+	discover.setPolicy("/services/v1/pay/refund", `{"allow":["Gateway"]}`)
+	discover.setPolicy("/services/v1/order/refund", `{"allow":["Report"]}`)
+	if err := call("Gateway", "refund"); err != nil {
+		t.Errorf("etcd policy should allow,%v", err)
+	}
+	if err := call("Gateway", "pay"); err != ErrAccessDenied {
+		t.Errorf("default deny fail,%v", err)
+	}
+	discover.setPolicy("/services/v1/pay/refund", "")
+	if err := call("Gateway", "refund"); err != ErrAccessDenied {
+		t.Errorf("removed policy should deny,%v", err)
+	}
+}
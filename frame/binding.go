@@ -0,0 +1,272 @@
+package frame
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kwins/iceberg/frame/validate"
+)
+
+// 表单和Header绑定
+// Bind 根据结构体字段的tag从表单(raw query和form表单)和Header中取值:
+//	type ListRequest struct {
+//		Page    int       `form:"page"`
+//		IDs     []int64   `form:"ids"`              // ids=1&ids=2 或 ids=1,2
+//		Since   time.Time `form:"since" time_format:"2006-01-02"`
+//		TraceID string    `header:"X-Trace-Id"`
+//		Ignore  string    `form:"-"`
+//	}
+// RAWQUERY和没有请求体的请求，没有form tag的字段使用json tag的名称(兼容生成的proto消息)，再没有使用字段名称;
+// JSON，XML，PROTOBUF请求先解析请求体，再绑定显式声明了form或header tag的字段;
+// 支持string，bool，整数，浮点数，time.Time，time.Duration，实现了encoding.TextUnmarshaler的类型，以及它们的切片和指针;
+// 值不合法时返回validate.Errors，对应400响应并列出所有不合法的字段;
+// 不支持路径参数:gateway按URL的最后一段路由到方法(/services/v1/hello/sayhi)，路径中没有参数可以绑定，参数需放在表单中
+
+const (
+	bindSourceForm   = "form"
+	bindSourceHeader = "header"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// defaultTimeFormats 没有time_format tag时依次尝试的时间格式，纯数字按unix秒解析
+var defaultTimeFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// isStructPtr 是否是结构体指针
+func isStructPtr(i interface{}) bool {
+	rv := reflect.ValueOf(i)
+	return rv.Kind() == reflect.Ptr && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct
+}
+
+// bindValues 将表单和Header绑定到结构体，implicit为true时没有tag的字段也从表单取值
+func bindValues(ptr interface{}, form url.Values, header http.Header, implicit bool) error {
+	if !isStructPtr(ptr) {
+		return fmt.Errorf("bind: pointer to struct required,got %T", ptr)
+	}
+	var errs validate.Errors
+	bindStruct(reflect.ValueOf(ptr).Elem(), form, header, implicit, &errs)
+	return errs.Err()
+}
+
+func bindStruct(rv reflect.Value, form url.Values, header http.Header, implicit bool, errs *validate.Errors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+		// 未导出的字段，嵌入的未导出结构体的导出字段仍可绑定
+		if sf.PkgPath != "" && !(sf.Anonymous && fv.Kind() == reflect.Struct) {
+			continue
+		}
+
+		if name, ok := sf.Tag.Lookup(bindSourceHeader); ok && name != "-" {
+			if vs, ok := header[http.CanonicalHeaderKey(name)]; ok {
+				bindField(fv, sf, name, bindSourceHeader, vs, errs)
+			}
+			continue
+		}
+
+		name, explicit := sf.Tag.Lookup(bindSourceForm)
+		if name == "-" {
+			continue
+		}
+		if explicit {
+			name = strings.Split(name, ",")[0]
+		}
+		if !explicit {
+			// 嵌入的结构体展开
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				bindStruct(fv, form, header, implicit, errs)
+				continue
+			}
+			if !implicit || sf.PkgPath != "" || !bindable(sf.Type) {
+				continue
+			}
+			name = implicitName(sf)
+			if name == "" {
+				continue
+			}
+		}
+		if vs, ok := form[name]; ok {
+			bindField(fv, sf, name, bindSourceForm, vs, errs)
+		}
+	}
+}
+
+// implicitName 没有form tag的字段名称，优先使用json tag
+func implicitName(sf reflect.StructField) string {
+	if tag, ok := sf.Tag.Lookup("json"); ok {
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+// bindable 是否是支持绑定的类型
+func bindable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+	}
+	if t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func bindField(fv reflect.Value, sf reflect.StructField, name, source string, vs []string, errs *validate.Errors) {
+	if !bindable(sf.Type) {
+		errs.Add(name, source, fmt.Sprintf("不支持绑定的类型%s", sf.Type))
+		return
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(sf.Type.Elem()))
+		}
+		fv = fv.Elem()
+	}
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		// 单个值时按逗号分隔
+		if len(vs) == 1 && strings.Contains(vs[0], ",") {
+			vs = strings.Split(vs[0], ",")
+		}
+		slice := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, v := range vs {
+			if err := setValue(slice.Index(i), sf, strings.TrimSpace(v)); err != nil {
+				errs.Add(name, source, fmt.Sprintf("的值%q不合法:%s", v, err.Error()))
+				return
+			}
+		}
+		fv.Set(slice)
+		return
+	}
+	if len(vs) == 0 {
+		return
+	}
+	if err := setValue(fv, sf, vs[0]); err != nil {
+		errs.Add(name, source, fmt.Sprintf("的值%q不合法:%s", vs[0], err.Error()))
+	}
+}
+
+func setValue(v reflect.Value, sf reflect.StructField, s string) error {
+	if v.Type() == timeType {
+		t, err := parseTime(s, sf.Tag.Get("time_format"))
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return numError(err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return numError(err)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("不支持的类型%s", v.Type())
+	}
+	return nil
+}
+
+// numError 去掉strconv错误中重复的函数名和输入值
+func numError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err
+	}
+	return err
+}
+
+func parseTime(s, layout string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if layout != "" {
+		return time.ParseInLocation(layout, s, time.Local)
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range defaultTimeFormats {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("时间格式应为%s", strings.Join(defaultTimeFormats, "或"))
+}
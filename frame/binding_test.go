@@ -0,0 +1,92 @@
+package frame
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/protocol"
+	"github.com/kwins/iceberg/frame/validate"
+)
+
+type bindEmbed struct {
+	Lang string `form:"lang"`
+}
+
+type bindRequest struct {
+	bindEmbed
+	Name    string        `json:"name,omitempty"`
+	Page    int           `form:"page"`
+	Size    *uint32       `form:"size"`
+	Score   float64       `form:"score"`
+	Debug   bool          `form:"debug"`
+	IDs     []int64       `form:"ids"`
+	Tags    []string      `form:"tag"`
+	Since   time.Time     `form:"since" time_format:"2006-01-02"`
+	Until   time.Time     `form:"until"`
+	Wait    time.Duration `form:"wait"`
+	TraceID string        `header:"X-Trace-Id"`
+	Ignore  string        `form:"-"`
+}
+
+func bindContext(format protocol.RestfulFormat, form map[string]string, body []byte) Context {
+	c := NewContext()
+	c.Reset(&protocol.Proto{
+		Format: format,
+		Form:   form,
+		Header: map[string]string{"X-Trace-Id": "t1"},
+		Body:   body,
+	}, &protocol.Proto{})
+	return c
+}
+
+func TestBindForm(t *testing.T) {
+	c := bindContext(protocol.RestfulFormat_RAWQUERY, map[string]string{
+		"name": "kwins", "page": "2", "size": "20", "score": "9.5", "debug": "true",
+		"ids": "1,2,3", "tag": "a", "since": "2018-01-02", "until": "1514822400",
+		"wait": "1s", "lang": "zh", "Ignore": "x",
+	}, nil)
+	var req bindRequest
+	if err := c.Bind(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Name != "kwins" || req.Page != 2 || req.Size == nil || *req.Size != 20 || req.Score != 9.5 || !req.Debug {
+		t.Errorf("scalar bind fail,%+v", req)
+	}
+	if len(req.IDs) != 3 || req.IDs[2] != 3 || len(req.Tags) != 1 || req.Tags[0] != "a" {
+		t.Errorf("slice bind fail,%+v", req)
+	}
+	if req.Since.Day() != 2 || req.Until.Unix() != 1514822400 || req.Wait != time.Second {
+		t.Errorf("time bind fail,%+v", req)
+	}
+	if req.Lang != "zh" || req.TraceID != "t1" || req.Ignore != "" {
+		t.Errorf("embed,header or ignore bind fail,%+v", req)
+	}
+}
+
+func TestBindBody(t *testing.T) {
+	// 请求体之外只绑定显式声明tag的字段
+	c := bindContext(protocol.RestfulFormat_JSON, map[string]string{"page": "3", "name": "form"}, []byte(`{"name":"body"}`))
+	var req bindRequest
+	if err := c.Bind(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Name != "body" || req.Page != 3 || req.TraceID != "t1" {
+		t.Errorf("body bind fail,%+v", req)
+	}
+}
+
+func TestBindError(t *testing.T) {
+	c := bindContext(protocol.RestfulFormat_RAWQUERY, map[string]string{
+		"page": "abc", "ids": "1,x", "since": "2018/01/02", "debug": "yes",
+	}, nil)
+	var req bindRequest
+	err := c.Bind(&req)
+	errs, ok := err.(validate.Errors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("want 4 field errors,got %v", err)
+	}
+	if errStatus(err) != 400 {
+		t.Errorf("bind error should be 400")
+	}
+	t.Log(err)
+}
@@ -1,17 +1,22 @@
 package frame
 
 import (
-	"github.com/kwins/iceberg/frame/protocol"
+	"context"
 	"net/http"
+	"net/url"
+
+	"github.com/kwins/iceberg/frame/protocol"
 )
 
 type callInfo struct {
 	maxReceiveMessageSize *int // TODO
 	maxSendMessageSize    *int // TODO
 	failFast              bool
-	form                  map[string]string
+	form                  url.Values
 	format                protocol.RestfulFormat
 	header                http.Header
+	selector              *MetaSelector
+	ctx                   context.Context
 }
 
 // CallOption 请求Option
@@ -34,7 +39,7 @@ type afterCall func(c *callInfo)
 func (o afterCall) before(c *callInfo) error { return nil }
 func (o afterCall) after(c *callInfo)        { o(c) }
 
-// Header 附加Header信息去请求
+// Header 附加Header信息去请求，同名Header的多个值都会发送
 func Header(header http.Header) CallOption {
 	return beforeCall(func(c *callInfo) error {
 		c.header = header
@@ -53,7 +58,56 @@ func Format(format protocol.RestfulFormat) CallOption {
 // From With form
 func From(f map[string]string) CallOption {
 	return beforeCall(func(c *callInfo) error {
-		c.form = f
+		c.form = make(url.Values, len(f))
+		for k, v := range f {
+			c.form.Set(k, v)
+		}
+		return nil
+	})
+}
+
+// FormValues 附加多值表单参数去请求
+func FormValues(form url.Values) CallOption {
+	return beforeCall(func(c *callInfo) error {
+		c.form = form
+		return nil
+	})
+}
+
+// Select 按实例元数据选择处理请求的实例
+func Select(sel MetaSelector) CallOption {
+	return beforeCall(func(c *callInfo) error {
+		c.selector = &sel
+		return nil
+	})
+}
+
+// SelectZone 只选择指定可用区的实例
+func SelectZone(zone string) CallOption {
+	return beforeCall(func(c *callInfo) error {
+		if c.selector == nil {
+			c.selector = new(MetaSelector)
+		}
+		c.selector.Zone = zone
+		return nil
+	})
+}
+
+// SelectTags 只选择带有全部标签的实例
+func SelectTags(tags ...string) CallOption {
+	return beforeCall(func(c *callInfo) error {
+		if c.selector == nil {
+			c.selector = new(MetaSelector)
+		}
+		c.selector.Tags = append(c.selector.Tags, tags...)
+		return nil
+	})
+}
+
+// WithContext 请求使用ctx的超时时间和取消信号，并带上ctx中的baggage
+func WithContext(ctx context.Context) CallOption {
+	return beforeCall(func(c *callInfo) error {
+		c.ctx = ctx
 		return nil
 	})
 }
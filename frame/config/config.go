@@ -3,7 +3,6 @@ package config
 import (
 	"encoding/json"
 	"os"
-	"time"
 )
 
 // MysqlCfg mysql config
@@ -27,14 +26,127 @@ type RedisCfg struct {
 
 // BaseCfg 服务基础配置
 type BaseCfg struct {
-	Etcd   EtcdCfg   `json:"etcdCfg"`
-	Zipkin ZipkinCfg `json:"zipkinCfg"`
-	Staff  StaffCfg  `json:"staffCfg"`
+	Etcd      EtcdCfg      `json:"etcdCfg"`
+	Zipkin    ZipkinCfg    `json:"zipkinCfg"`
+	Trace     TraceCfg     `json:"traceCfg"`
+	Staff     StaffCfg     `json:"staffCfg"`
+	Meta      MetaCfg      `json:"metaCfg"`
+	Locality  LocalityCfg  `json:"localityCfg"`
+	Outlier   OutlierCfg   `json:"outlierCfg"`
+	Log       LogCfg       `json:"logCfg"`
+	AccessLog AccessLogCfg `json:"accessLogCfg"`
+	Metadata  MetadataCfg  `json:"metadataCfg"`
+	ACL       ACLCfg       `json:"aclCfg"`
+}
+
+// ACLCfg 服务间调用的访问控制配置
+// 调用方总是在请求中带上自己的服务名称，配置了Secret时同时签名;Enable时服务端按策略检查调用方;
+// PolicyFile 不为空时从本地JSON文件加载策略，不再使用etcd中的策略，用于测试
+type ACLCfg struct {
+	Enable      bool   `json:"enable" yaml:"enable"`             // 开启服务端检查
+	DefaultDeny bool   `json:"default_deny" yaml:"default_deny"` // 没有策略时拒绝调用
+	Secret      string `json:"secret" yaml:"secret"`             // 签名密钥，所有服务需一致，为空时不签名也不校验签名
+	MaxSkew     int    `json:"max_skew" yaml:"max_skew"`         // 签名时间的最大偏差，单位秒，默认300
+	PolicyFile  string `json:"policy_file" yaml:"policy_file"`   // 本地策略文件
+}
+
+// MetadataCfg 请求元数据配置
+// Forward 中的Header从收到的请求自动透传到下游请求;
+// gateway收到TrustedProxies以外的客户端请求时，删除Forward中的Header和baggage
+type MetadataCfg struct {
+	Forward        []string `json:"forward" yaml:"forward"`                 // 如 X-Tenant-Id,X-User-Id,Accept-Language
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"` // 可信客户端的IP或CIDR，访问日志只信任其转发的客户端IP
+}
+
+// AccessLogCfg 访问日志配置
+// Fields 可选 method,uri,serve_method,status,latency,req_size,resp_size,bizid,request_id,
+// client_ip,header,form,req_body,resp_body,error
+type AccessLogCfg struct {
+	Disable    bool     `json:"disable" yaml:"disable"`         // 关闭访问日志
+	Fields     []string `json:"fields" yaml:"fields"`           // 输出的字段，默认 method,uri,status,latency,req_size,resp_size,bizid,client_ip
+	SampleRate float64  `json:"sample_rate" yaml:"sample_rate"` // 成功请求的采样率(0,1]，默认1，失败的请求全部输出
+	Redact     []string `json:"redact" yaml:"redact"`           // 脱敏的header,form,body字段，默认 Authorization,Cookie,Set-Cookie,password,token
+	MaxBody    int      `json:"max_body" yaml:"max_body"`       // 输出body的最大长度，0不输出body
+}
+
+// LogCfg 日志配置，File为空时输出到控制台
+// File可以包含时间变量 %Y %M %D %H %m，如 logs/hello.%Y%M%D%H.log 按小时轮转
+type LogCfg struct {
+	Format   string `json:"format" yaml:"format"`       // text(默认)，json，logfmt
+	File     string `json:"file" yaml:"file"`           // 日志文件路径
+	Level    string `json:"level" yaml:"level"`         // 日志级别
+	Pattern  string `json:"pattern" yaml:"pattern"`     // 追加在文件路径后的时间模式，默认 .%Y-%M-%D
+	MaxSize  int    `json:"max_size" yaml:"max_size"`   // 单个文件最大MB，0不限制
+	MaxFiles int    `json:"max_files" yaml:"max_files"` // 最多保留的轮转文件数，0不限制
+	MaxAge   int    `json:"max_age" yaml:"max_age"`     // 轮转文件保留天数，0不限制
+	Compress bool   `json:"compress" yaml:"compress"`   // gzip压缩轮转文件
+
+	// 配置Sinks后日志经异步队列写入所有Sink，File不再生效
+	Sinks        []SinkCfg `json:"sinks" yaml:"sinks"`
+	QueueSize    int       `json:"queue_size" yaml:"queue_size"`         // 异步队列长度，默认4096
+	DropWhenFull bool      `json:"drop_when_full" yaml:"drop_when_full"` // 队列满时丢弃日志，默认阻塞等待
+}
+
+// SinkCfg 日志输出配置
+type SinkCfg struct {
+	Type  string `json:"type" yaml:"type"`   // console，file，syslog，udp，tcp
+	Addr  string `json:"addr" yaml:"addr"`   // 文件路径或网络地址，syslog为空表示本机
+	Level string `json:"level" yaml:"level"` // 该输出的最低级别，默认全部输出
+	Tag   string `json:"tag" yaml:"tag"`     // syslog tag，默认服务名称
+}
+
+// MetaCfg 实例元数据配置，注册到etcd中供路由选择实例
+type MetaCfg struct {
+	Zone   string            `json:"zone" yaml:"zone"`     // 可用区
+	Region string            `json:"region" yaml:"region"` // 地域
+	Weight int               `json:"weight" yaml:"weight"` // 权重，默认100
+	Tags   []string          `json:"tags" yaml:"tags"`     // 实例标签，用于灰度路由
+	Labels map[string]string `json:"labels" yaml:"labels"` // 自定义标签
+	Build  string            `json:"build" yaml:"build"`   // 构建信息
 }
 
 // ZipkinCfg Zipkin配置
 type ZipkinCfg struct {
-	EndPoints string `json:"endpoints"`
+	EndPoints string `json:"endpoints" yaml:"endpoints"` // zipkin collector地址，为空时不开启链路追踪
+}
+
+// TraceCfg 链路追踪配置
+// Backend 为otlp时通过OTLP发送到OpenTelemetry collector，使用W3C traceparent传播;
+// 为zipkin时发送到Endpoint指定的zipkin;为空时兼容zipkinCfg
+type TraceCfg struct {
+	Backend    string            `json:"backend" yaml:"backend"`         // otlp,zipkin
+	Endpoint   string            `json:"endpoint" yaml:"endpoint"`       // collector地址，如 127.0.0.1:4318
+	Protocol   string            `json:"protocol" yaml:"protocol"`       // otlp协议 http(默认),grpc
+	Insecure   bool              `json:"insecure" yaml:"insecure"`       // 不使用TLS
+	Headers    map[string]string `json:"headers" yaml:"headers"`         // 发送到collector时附加的header
+	SampleRate float64           `json:"sample_rate" yaml:"sample_rate"` // 采样率，默认1，上游已采样的请求总是采样
+	Attributes map[string]string `json:"attributes" yaml:"attributes"`   // 额外的resource属性
+}
+
+// LocalityCfg 就近路由配置
+// 开启后优先调用与自己同可用区的实例，本可用区健康实例数少于MinHealthy，
+// 或健康实例占比低于MinPercent时，溢出到同地域及其他可用区
+type LocalityCfg struct {
+	Enable     bool `json:"enable" yaml:"enable"`
+	MinHealthy int  `json:"min_healthy" yaml:"min_healthy"` // 默认1
+	MinPercent int  `json:"min_percent" yaml:"min_percent"` // 0-100，默认0不限制
+}
+
+// OutlierCfg 异常实例检测配置，时间单位为秒，未配置的项使用默认值
+type OutlierCfg struct {
+	Enable              bool    `json:"enable" yaml:"enable"`
+	Interval            int     `json:"interval" yaml:"interval"`                         // 统计周期，默认10
+	ConsecutiveErrors   int     `json:"consecutive_errors" yaml:"consecutive_errors"`     // 连续失败摘除阈值，默认5
+	ConsecutiveTimeouts int     `json:"consecutive_timeouts" yaml:"consecutive_timeouts"` // 连续超时摘除阈值，默认3
+	ErrorPercent        int     `json:"error_percent" yaml:"error_percent"`               // 周期内错误率摘除阈值(%)，0不检测
+	MinRequests         int     `json:"min_requests" yaml:"min_requests"`                 // 周期内最少请求数，默认20
+	LatencyFactor       float64 `json:"latency_factor" yaml:"latency_factor"`             // 平均延迟超过同组中位数的倍数时摘除，0不检测
+	BaseEjection        int     `json:"base_ejection" yaml:"base_ejection"`               // 基础摘除时长，默认30
+	MaxEjection         int     `json:"max_ejection" yaml:"max_ejection"`                 // 最大摘除时长，默认300
+	MaxEjectionPercent  int     `json:"max_ejection_percent" yaml:"max_ejection_percent"` // 同组最多摘除比例(%)，默认50
+	HealthCheck         bool    `json:"health_check" yaml:"health_check"`                 // 开启主动TCP检测
+	CheckInterval       int     `json:"check_interval" yaml:"check_interval"`             // 主动检测间隔
+	CheckTimeout        int     `json:"check_timeout" yaml:"check_timeout"`               // 主动检测超时，单位毫秒，默认500
 }
 
 // StaffCfg 服务监控人员
@@ -46,22 +158,25 @@ type StaffCfg struct {
 
 // EtcdCfg 对应配置文件中关于etcd配置内容
 type EtcdCfg struct {
-	EndPoints []string      `json:"endpoints" yaml:"endpoints"`
-	User      string        `json:"user" yaml:"user"`
-	Psw       string        `json:"psw" yaml:"psw"`
-	Timeout   time.Duration `json:"timeout" yaml:"timeout"`
+	EndPoints []string `json:"endpoints" yaml:"endpoints"`
+	User      string   `json:"user" yaml:"user"`
+	Psw       string   `json:"psw" yaml:"psw"`
+	Timeout   int      `json:"timeout" yaml:"timeout"` // 连接超时，单位秒
 }
 
-// Parseconfig parse json config
+// Parseconfig parse json config,panic when fail
 // out must be pointer
+// Deprecated: use Load instead
 func Parseconfig(filepath string, out interface{}) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		panic(err)
 	}
+	defer file.Close()
 	decoder := json.NewDecoder(file)
 	err = decoder.Decode(out)
 	if err != nil {
 		panic(err)
 	}
+	setLocalFile(filepath)
 }
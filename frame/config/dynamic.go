@@ -0,0 +1,359 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 动态配置
+// 服务的配置由三部分合并而成，优先级从低到高为：
+// 本地配置文件 < etcd中的配置(/services/v1/<svc>/provider/config) < 环境变量
+// etcd中的配置修改后实时生效，更新前会经过校验，校验失败时保持原配置不变
+// 配置项使用点号分隔的路径访问，如 mysqlCfg.Host.read 对应环境变量 ICEBERG_MYSQLCFG_HOST_READ
+
+var errNoHistory = errors.New("no config history to rollback")
+
+// maxHistory 保留的历史配置版本数
+const maxHistory = 8
+
+// Values 一份配置的内容
+type Values map[string]interface{}
+
+// Validator 配置校验函数，返回错误时拒绝本次更新
+type Validator func(next Values) error
+
+// Watcher 配置变化回调
+// key 订阅的配置项; old,new 变化前后的值，配置项不存在时为nil
+type Watcher func(key string, old, new interface{})
+
+type watcher struct {
+	key string
+	fn  Watcher
+}
+
+// Dynamic 动态配置中心
+type Dynamic struct {
+	locker    sync.RWMutex
+	local     Values
+	remote    Values
+	merged    Values
+	history   []Values // 历史远端配置，用于回滚
+	envPrefix string
+
+	validators []Validator
+	watchers   []watcher
+}
+
+var defaultDynamic = NewDynamic()
+
+// Default 默认的动态配置，框架会把etcd中本服务的配置同步到这里
+func Default() *Dynamic {
+	return defaultDynamic
+}
+
+// NewDynamic new dynamic config
+func NewDynamic() *Dynamic {
+	return &Dynamic{
+		local:     Values{},
+		remote:    Values{},
+		merged:    Values{},
+		envPrefix: "ICEBERG",
+	}
+}
+
+// SetEnvPrefix 设置环境变量前缀，为空表示不读取环境变量
+func (d *Dynamic) SetEnvPrefix(prefix string) {
+	d.locker.Lock()
+	d.envPrefix = prefix
+	d.locker.Unlock()
+}
+
+// LoadFile 加载本地配置文件，格式，include和profile与Load相同
+// 服务启动时框架会加载config.LocalFile()
+func (d *Dynamic) LoadFile(filepath string) error {
+	local, err := loadValues(filepath, os.Getenv("ICEBERG_PROFILE"))
+	if err != nil {
+		return err
+	}
+
+	d.locker.Lock()
+	next := merge(local, d.remote)
+	if err := d.validate(next); err != nil {
+		d.locker.Unlock()
+		return err
+	}
+	old := d.merged
+	d.local, d.merged = local, next
+	d.locker.Unlock()
+	d.notify(old, next)
+	return nil
+}
+
+// Update 使用etcd中的配置内容更新，b为空表示删除远端配置
+// 校验失败时返回错误，配置保持不变
+func (d *Dynamic) Update(b []byte) error {
+	var remote = Values{}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &remote); err != nil {
+			return fmt.Errorf("parse remote config fail,%s", err.Error())
+		}
+	}
+
+	d.locker.Lock()
+	next := merge(d.local, remote)
+	if err := d.validate(next); err != nil {
+		d.locker.Unlock()
+		return err
+	}
+	d.history = append(d.history, d.remote)
+	if len(d.history) > maxHistory {
+		d.history = d.history[1:]
+	}
+	old := d.merged
+	d.remote, d.merged = remote, next
+	d.locker.Unlock()
+	d.notify(old, next)
+	return nil
+}
+
+// Rollback 回滚到上一个版本的远端配置
+// 上一个版本需要通过当前的校验函数，校验失败时返回错误，配置和历史版本保持不变
+func (d *Dynamic) Rollback() error {
+	d.locker.Lock()
+	if len(d.history) == 0 {
+		d.locker.Unlock()
+		return errNoHistory
+	}
+	remote := d.history[len(d.history)-1]
+	next := merge(d.local, remote)
+	if err := d.validate(next); err != nil {
+		d.locker.Unlock()
+		return err
+	}
+	d.history = d.history[:len(d.history)-1]
+	old := d.merged
+	d.remote, d.merged = remote, next
+	d.locker.Unlock()
+	d.notify(old, next)
+	return nil
+}
+
+// Validate 添加配置校验函数，已有配置不会被重新校验
+func (d *Dynamic) Validate(fn Validator) {
+	d.locker.Lock()
+	d.validators = append(d.validators, fn)
+	d.locker.Unlock()
+}
+
+// Subscribe 订阅配置项的变化，key为空表示订阅所有变化
+func (d *Dynamic) Subscribe(key string, fn Watcher) {
+	d.locker.Lock()
+	d.watchers = append(d.watchers, watcher{key: key, fn: fn})
+	d.locker.Unlock()
+}
+
+// Get 获取配置项，环境变量优先
+func (d *Dynamic) Get(key string) (interface{}, bool) {
+	d.locker.RLock()
+	prefix, merged := d.envPrefix, d.merged
+	d.locker.RUnlock()
+	if prefix != "" {
+		if v, ok := os.LookupEnv(envName(prefix, key)); ok {
+			return v, true
+		}
+	}
+	return merged.Get(key)
+}
+
+// GetString 获取字符串配置项
+func (d *Dynamic) GetString(key, defaultValue string) string {
+	if v, ok := d.Get(key); ok {
+		return toString(v)
+	}
+	return defaultValue
+}
+
+// GetInt 获取整数配置项
+func (d *Dynamic) GetInt(key string, defaultValue int) int {
+	if v, ok := d.Get(key); ok {
+		if f, err := toFloat(v); err == nil {
+			return int(f)
+		}
+	}
+	return defaultValue
+}
+
+// GetFloat 获取浮点数配置项
+func (d *Dynamic) GetFloat(key string, defaultValue float64) float64 {
+	if v, ok := d.Get(key); ok {
+		if f, err := toFloat(v); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+// GetBool 获取布尔配置项
+func (d *Dynamic) GetBool(key string, defaultValue bool) bool {
+	if v, ok := d.Get(key); ok {
+		switch b := v.(type) {
+		case bool:
+			return b
+		case string:
+			if r, err := strconv.ParseBool(b); err == nil {
+				return r
+			}
+		}
+	}
+	return defaultValue
+}
+
+// GetDuration 获取时间配置项，支持 "3s" 格式，数字表示秒
+func (d *Dynamic) GetDuration(key string, defaultValue time.Duration) time.Duration {
+	if v, ok := d.Get(key); ok {
+		if s, ok := v.(string); ok {
+			if r, err := time.ParseDuration(s); err == nil {
+				return r
+			}
+		}
+		if f, err := toFloat(v); err == nil {
+			return time.Duration(f * float64(time.Second))
+		}
+	}
+	return defaultValue
+}
+
+// GetStrings 获取字符串数组配置项，环境变量使用逗号分隔
+func (d *Dynamic) GetStrings(key string, defaultValue []string) []string {
+	v, ok := d.Get(key)
+	if !ok {
+		return defaultValue
+	}
+	switch arr := v.(type) {
+	case string:
+		return strings.Split(arr, ",")
+	case []interface{}:
+		var r = make([]string, 0, len(arr))
+		for _, e := range arr {
+			r = append(r, toString(e))
+		}
+		return r
+	}
+	return defaultValue
+}
+
+// Unmarshal 将配置项解析到结构体中，key为空表示整个配置
+func (d *Dynamic) Unmarshal(key string, out interface{}) error {
+	d.locker.RLock()
+	merged := d.merged
+	d.locker.RUnlock()
+	var v interface{} = map[string]interface{}(merged)
+	if key != "" {
+		var ok bool
+		if v, ok = merged.Get(key); !ok {
+			return fmt.Errorf("config %s not found", key)
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// validate 校验配置，调用方需持有锁
+func (d *Dynamic) validate(next Values) error {
+	for _, fn := range d.validators {
+		if err := fn(next); err != nil {
+			return fmt.Errorf("config validate fail,%s", err.Error())
+		}
+	}
+	return nil
+}
+
+func (d *Dynamic) notify(old, next Values) {
+	d.locker.RLock()
+	watchers := d.watchers
+	d.locker.RUnlock()
+	for _, w := range watchers {
+		var o, n interface{}
+		if w.key == "" {
+			o, n = map[string]interface{}(old), map[string]interface{}(next)
+		} else {
+			o, _ = old.Get(w.key)
+			n, _ = next.Get(w.key)
+		}
+		if !reflect.DeepEqual(o, n) {
+			w.fn(w.key, o, n)
+		}
+	}
+}
+
+// Get 按点号分隔的路径获取配置项
+func (v Values) Get(key string) (interface{}, bool) {
+	var cur interface{} = map[string]interface{}(v)
+	for _, k := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// merge 深度合并配置，src覆盖dst，返回新的配置
+func merge(dst, src Values) Values {
+	var r = make(Values, len(dst))
+	for k, v := range dst {
+		r[k] = v
+	}
+	for k, v := range src {
+		sm, sok := v.(map[string]interface{})
+		dm, dok := r[k].(map[string]interface{})
+		if sok && dok {
+			r[k] = map[string]interface{}(merge(dm, sm))
+		} else {
+			r[k] = v
+		}
+	}
+	return r
+}
+
+func envName(prefix, key string) string {
+	return strings.ToUpper(prefix + "_" + strings.Replace(key, ".", "_", -1))
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	case bool:
+		if n {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
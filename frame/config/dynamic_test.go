@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDynamic(t *testing.T) {
+	d := NewDynamic()
+	d.Validate(func(next Values) error {
+		if v, ok := next.Get("redisCfg.DBNo"); ok && v.(float64) < 0 {
+			return errors.New("DBNo must >= 0")
+		}
+		return nil
+	})
+	var changed int
+	d.Subscribe("redisCfg.Addr", func(key string, old, new interface{}) {
+		changed++
+	})
+
+	if err := d.Update([]byte(`{"redisCfg":{"Addr":"127.0.0.1:6379","DBNo":1}}`)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if addr := d.GetString("redisCfg.Addr", ""); addr != "127.0.0.1:6379" {
+		t.Errorf("GetString got %s", addr)
+	}
+	if db := d.GetInt("redisCfg.DBNo", -1); db != 1 {
+		t.Errorf("GetInt got %d", db)
+	}
+
+	// 校验失败保持原配置
+	if err := d.Update([]byte(`{"redisCfg":{"Addr":"127.0.0.1:6380","DBNo":-1}}`)); err == nil {
+		t.Error("bad config should be rejected")
+	}
+	if addr := d.GetString("redisCfg.Addr", ""); addr != "127.0.0.1:6379" {
+		t.Errorf("rejected config applied, got %s", addr)
+	}
+
+	if err := d.Update([]byte(`{"redisCfg":{"Addr":"127.0.0.1:6380","DBNo":2}}`)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := d.Rollback(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if addr := d.GetString("redisCfg.Addr", ""); addr != "127.0.0.1:6379" {
+		t.Errorf("rollback got %s", addr)
+	}
+	if changed != 3 {
+		t.Errorf("watcher called %d times", changed)
+	}
+
+	// 回滚的版本也需要通过校验
+	d.Update([]byte(`{"redisCfg":{"Addr":"127.0.0.1:6381"}}`))
+	d.Validate(func(next Values) error {
+		if addr, _ := next.Get("redisCfg.Addr"); addr != "127.0.0.1:6381" {
+			return errors.New("addr not allowed")
+		}
+		return nil
+	})
+	if err := d.Rollback(); err == nil {
+		t.Error("rollback to invalid config should fail")
+	}
+	if addr := d.GetString("redisCfg.Addr", ""); addr != "127.0.0.1:6381" {
+		t.Errorf("failed rollback changed config to %s", addr)
+	}
+
+	os.Setenv("ICEBERG_REDISCFG_ADDR", "10.0.0.1:6379")
+	defer os.Unsetenv("ICEBERG_REDISCFG_ADDR")
+	if addr := d.GetString("redisCfg.Addr", ""); addr != "10.0.0.1:6379" {
+		t.Errorf("env override got %s", addr)
+	}
+}
+
+func TestDynamicLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iceberg-dynamic")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	p := writeFile(t, dir, "svc.yaml", `
+redisCfg:
+  Addr: 127.0.0.1:6379
+  DBNo: 1
+`)
+	var cfg struct {
+		Redis RedisCfg `json:"redisCfg"`
+	}
+	if err := Load(p, &cfg, EnvPrefix("")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if LocalFile() != p {
+		t.Errorf("local file got %s", LocalFile())
+	}
+
+	// 本地配置 < etcd中的配置
+	d := NewDynamic()
+	if err := d.LoadFile(LocalFile()); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := d.Update([]byte(`{"redisCfg":{"DBNo":2}}`)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if addr, db := d.GetString("redisCfg.Addr", ""), d.GetInt("redisCfg.DBNo", 0); addr != "127.0.0.1:6379" || db != 2 {
+		t.Errorf("merged config got %s %d", addr, db)
+	}
+}
@@ -0,0 +1,402 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// 配置加载
+// 按文件扩展名选择解析方式，支持 .json .yaml .yml .toml，所有格式都使用json tag映射字段，
+// 字段的yaml tag与json tag不同时，yaml tag作为别名
+// 文件内容中的 ${ENV} 或 ${ENV:default} 会被替换为环境变量的值
+// 顶层的 include 字段列出需要先加载的文件(相对当前文件所在目录)，当前文件覆盖被包含的文件
+// 指定profile时会在最后加载同目录下的 <name>.<profile>.<ext>，如 gw.json => gw.dev.json
+// 环境变量覆盖配置项，名称为 前缀_路径，如 ICEBERG_ETCDCFG_ENDPOINTS，数组使用逗号分隔
+// 字段带有 validate:"required" tag 时不能为空
+
+const includeKey = "include"
+
+type loadOptions struct {
+	envPrefix string
+	profile   string
+}
+
+// LoadOption 配置加载选项
+type LoadOption func(*loadOptions)
+
+// EnvPrefix 环境变量前缀，默认为ICEBERG，为空时不使用环境变量覆盖
+func EnvPrefix(prefix string) LoadOption {
+	return func(o *loadOptions) { o.envPrefix = prefix }
+}
+
+// Profile 配置环境，如 dev，test，prod，默认读取环境变量 ICEBERG_PROFILE
+func Profile(profile string) LoadOption {
+	return func(o *loadOptions) { o.profile = profile }
+}
+
+// Load 加载配置文件到out中，out必须是指针
+func Load(path string, out interface{}, opts ...LoadOption) error {
+	o := loadOptions{envPrefix: "ICEBERG", profile: os.Getenv("ICEBERG_PROFILE")}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	values, err := loadValues(path, o.profile)
+	if err != nil {
+		return err
+	}
+	setLocalFile(path)
+
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("config out must be a non-nil pointer")
+	}
+	b, err := json.Marshal(coerce(map[string]interface{}(values), rv.Elem().Type()))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("decode %s fail,%s", path, err.Error())
+	}
+	if o.envPrefix != "" {
+		if err := applyEnv(rv.Elem(), strings.ToUpper(o.envPrefix)); err != nil {
+			return err
+		}
+	}
+	return validateRequired(rv.Elem(), "")
+}
+
+// localFile 最近一次Load加载的配置文件，服务启动时作为config.Default()的本地配置
+var localFile string
+var localFileLocker sync.RWMutex
+
+func setLocalFile(path string) {
+	localFileLocker.Lock()
+	localFile = path
+	localFileLocker.Unlock()
+}
+
+// LocalFile 最近一次Load或Parseconfig加载的配置文件路径
+func LocalFile() string {
+	localFileLocker.RLock()
+	defer localFileLocker.RUnlock()
+	return localFile
+}
+
+// loadValues 解析配置文件，包含的文件和profile对应的文件
+func loadValues(path, profile string) (Values, error) {
+	values, err := loadFile(path, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if profile != "" {
+		ext := filepath.Ext(path)
+		profilePath := strings.TrimSuffix(path, ext) + "." + profile + ext
+		if _, err := os.Stat(profilePath); err == nil {
+			pv, err := loadFile(profilePath, map[string]bool{})
+			if err != nil {
+				return nil, err
+			}
+			values = merge(values, pv)
+		}
+	}
+	return values, nil
+}
+
+// loadFile 解析单个文件及其包含的文件
+func loadFile(path string, loading map[string]bool) (Values, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if loading[abs] {
+		return nil, fmt.Errorf("config include cycle at %s", path)
+	}
+	loading[abs] = true
+	defer delete(loading, abs)
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b = []byte(expandEnv(string(b)))
+
+	var values Values
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(b, &values)
+	case ".yaml", ".yml":
+		var raw map[interface{}]interface{}
+		if err = yaml.Unmarshal(b, &raw); err == nil {
+			values, _ = normalize(raw).(map[string]interface{})
+		}
+	case ".toml":
+		err = toml.Unmarshal(b, &values)
+	default:
+		return nil, fmt.Errorf("not support config format %s", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s fail,%s", path, err.Error())
+	}
+	if values == nil {
+		values = Values{}
+	}
+
+	includes, ok := values[includeKey]
+	if !ok {
+		return values, nil
+	}
+	delete(values, includeKey)
+
+	var files []string
+	switch inc := includes.(type) {
+	case string:
+		files = []string{inc}
+	case []interface{}:
+		for _, f := range inc {
+			files = append(files, fmt.Sprint(f))
+		}
+	}
+	var base = Values{}
+	for _, f := range files {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(filepath.Dir(path), f)
+		}
+		iv, err := loadFile(f, loading)
+		if err != nil {
+			return nil, err
+		}
+		base = merge(base, iv)
+	}
+	return merge(base, values), nil
+}
+
+// expandEnv 替换 ${ENV} 和 ${ENV:default}
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if i := strings.IndexByte(name, ':'); i != -1 {
+			if v, ok := os.LookupEnv(name[:i]); ok {
+				return v
+			}
+			return name[i+1:]
+		}
+		return os.Getenv(name)
+	})
+}
+
+// normalize 将yaml解析出的map[interface{}]interface{}转为map[string]interface{}
+func normalize(v interface{}) interface{} {
+	switch m := v.(type) {
+	case map[interface{}]interface{}:
+		r := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			r[fmt.Sprint(k)] = normalize(e)
+		}
+		return r
+	case []interface{}:
+		for i := range m {
+			m[i] = normalize(m[i])
+		}
+		return m
+	}
+	return v
+}
+
+// coerce 按目标类型修正标量的类型
+// yaml和toml中未加引号的 123456 会被解析为数字，目标字段为字符串时转为字符串
+func coerce(v interface{}, t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		switch v.(type) {
+		case int, int64, uint64, float64, bool:
+			return fmt.Sprint(v)
+		}
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			aliasKey(m, fieldName(f), yamlName(f))
+			for k, e := range m {
+				if strings.EqualFold(k, fieldName(f)) {
+					m[k] = coerce(e, f.Type)
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := v.([]interface{}); ok {
+			for i := range arr {
+				arr[i] = coerce(arr[i], t.Elem())
+			}
+		}
+	case reflect.Map:
+		if m, ok := v.(map[string]interface{}); ok {
+			for k, e := range m {
+				m[k] = coerce(e, t.Elem())
+			}
+		}
+	}
+	return v
+}
+
+// fieldName 字段在配置中的名称，优先使用json tag
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// yamlName 字段的yaml tag名称
+func yamlName(f reflect.StructField) string {
+	if tag := f.Tag.Get("yaml"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "-" {
+			return name
+		}
+	}
+	return ""
+}
+
+// aliasKey 将使用别名的配置项改为字段名称，已有字段名称时忽略别名
+func aliasKey(m map[string]interface{}, name, alias string) {
+	if alias == "" || strings.EqualFold(alias, name) {
+		return
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return
+		}
+	}
+	for k, e := range m {
+		if strings.EqualFold(k, alias) {
+			delete(m, k)
+			m[name] = e
+			return
+		}
+	}
+}
+
+// applyEnv 使用环境变量覆盖配置项
+func applyEnv(v reflect.Value, name string) error {
+	if v.Kind() == reflect.Struct {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				continue
+			}
+			if err := applyEnv(v.Field(i), name+"_"+strings.ToUpper(fieldName(f))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	if err := setValue(v, s); err != nil {
+		return fmt.Errorf("env %s=%s invalid,%s", name, s, err.Error())
+	}
+	return nil
+}
+
+// setValue 将字符串转换为字段的类型并赋值，与配置文件中的数值含义相同
+func setValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setValue(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	default:
+		return fmt.Errorf("not support type %s", v.Type())
+	}
+	return nil
+}
+
+// validateRequired 检查带有 validate:"required" tag 的字段不为空
+func validateRequired(v reflect.Value, path string) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := fieldName(f)
+		if path != "" {
+			name = path + "." + name
+		}
+		fv := v.Field(i)
+		if strings.Contains(f.Tag.Get("validate"), "required") && isZero(fv) {
+			return fmt.Errorf("config %s is required", name)
+		}
+		if err := validateRequired(fv, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
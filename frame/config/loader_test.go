@@ -0,0 +1,112 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	return p
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iceberg-config")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "common.toml", `
+[etcdCfg]
+endpoints = ["http://127.0.0.1:2379"]
+user = "iceberg"
+timeout = 3
+`)
+	p := writeFile(t, dir, "conf.yaml", `
+include: common.toml
+etcdCfg:
+  psw: ${ICEBERG_TEST_PSW:123456}
+staffCfg:
+  name: hello
+`)
+	writeFile(t, dir, "conf.dev.yaml", `
+staffCfg:
+  email: dev@iceberg.com
+`)
+
+	var cfg BaseCfg
+	if err := Load(p, &cfg, Profile("dev")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if cfg.Etcd.User != "iceberg" || cfg.Etcd.Timeout != 3 || len(cfg.Etcd.EndPoints) != 1 {
+		t.Errorf("include not loaded,%+v", cfg.Etcd)
+	}
+	if cfg.Etcd.Psw != "123456" {
+		t.Errorf("env default not expanded,got %s", cfg.Etcd.Psw)
+	}
+	if cfg.Staff.Name != "hello" || cfg.Staff.Email != "dev@iceberg.com" {
+		t.Errorf("profile not merged,%+v", cfg.Staff)
+	}
+
+	os.Setenv("ICEBERG_ETCDCFG_ENDPOINTS", "http://10.0.0.1:2379,http://10.0.0.2:2379")
+	os.Setenv("ICEBERG_LOCALITYCFG_ENABLE", "true")
+	defer os.Unsetenv("ICEBERG_ETCDCFG_ENDPOINTS")
+	defer os.Unsetenv("ICEBERG_LOCALITYCFG_ENABLE")
+	cfg = BaseCfg{}
+	if err := Load(p, &cfg); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(cfg.Etcd.EndPoints) != 2 || cfg.Etcd.EndPoints[1] != "http://10.0.0.2:2379" {
+		t.Errorf("env override fail,%v", cfg.Etcd.EndPoints)
+	}
+	if !cfg.Locality.Enable {
+		t.Error("env override bool fail")
+	}
+
+	// yaml tag作为别名
+	alias := writeFile(t, dir, "alias.json", `{"staffCfg":{"mobile":"10086"},"flagName":"on"}`)
+	var aliasCfg struct {
+		Staff StaffCfg `json:"staffCfg"`
+		Flag  string   `json:"flag_name" yaml:"flagName"`
+	}
+	if err := Load(alias, &aliasCfg, EnvPrefix("")); err != nil {
+		t.Fatal(err.Error())
+	}
+	if aliasCfg.Flag != "on" || aliasCfg.Staff.MobilePhone != "10086" {
+		t.Errorf("yaml alias fail,%+v", aliasCfg)
+	}
+
+	// 缺少必填项
+	bad := writeFile(t, dir, "bad.json", `{"staffCfg":{"name":"hello"}}`)
+	var badCfg struct {
+		Etcd EtcdCfg `json:"etcdCfg"`
+		Addr string  `json:"addr" validate:"required"`
+	}
+	if err := Load(bad, &badCfg, EnvPrefix("")); err == nil {
+		t.Error("missing required field should fail")
+	} else {
+		t.Log(err.Error())
+	}
+}
+
+func TestParseconfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "iceberg-config")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	// 兼容旧的配置，不校验必填项，timeout单位为秒
+	p := writeFile(t, dir, "old.json", `{"etcdCfg":{"timeout":3}}`)
+	var cfg BaseCfg
+	Parseconfig(p, &cfg)
+	if cfg.Etcd.Timeout != 3 || LocalFile() != p {
+		t.Errorf("parse config fail,%+v", cfg.Etcd)
+	}
+}
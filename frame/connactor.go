@@ -8,8 +8,9 @@ import (
 	"sync/atomic"
 	"time"
 
-	log "github.com/kwins/iceberg/frame/icelog"
 	"github.com/kwins/iceberg/frame/protocol"
+
+	"github.com/opentracing/opentracing-go"
 )
 
 const (
@@ -113,13 +114,13 @@ func (connActor *ConnActor) initConnActor(c net.Conn) {
 							}
 						} else {
 							// 准备重发
-							log.Warnf("send data to %s fail,repush to send chan len=%d", connActor.RemoteAddr(), len(msg))
+							connLog.Warnf("send data to %s fail,repush to send chan len=%d", connActor.RemoteAddr(), len(msg))
 							connActor.sendChan <- msg
 							continue
 						}
 					} else {
 						failCount = 0
-						log.Debugf("send data to %s finished,data len=%d", connActor.RemoteAddr(), len(msg))
+						connLog.Debugf("send data to %s finished,data len=%d", connActor.RemoteAddr(), len(msg))
 					}
 				}
 			case <-connActor.ctx.Done():
@@ -131,7 +132,7 @@ func (connActor *ConnActor) initConnActor(c net.Conn) {
 							return
 						}
 						req.UnSerialize(msg)
-						log.Warnf("drop msg %s now, msg len=%d", req.GetBizid(), len(msg))
+						connLog.Warnf("drop msg %s now, msg len=%d", req.GetBizid(), len(msg))
 						connActor.requestHolder.Delete(req.RequestID)
 					default:
 						return
@@ -161,6 +162,12 @@ func (connActor *ConnActor) Write(b []byte) error {
 
 // RequestAndReponse 向特定的服务发送请求，并等待响应
 func (connActor *ConnActor) RequestAndReponse(b []byte,
+	requstID int64) (*protocol.Proto, error) {
+	return connActor.RequestWithContext(context.Background(), b, requstID)
+}
+
+// RequestWithContext 向特定的服务发送请求，并等待响应，ctx超时或取消时放弃等待
+func (connActor *ConnActor) RequestWithContext(ctx context.Context, b []byte,
 	requstID int64) (*protocol.Proto, error) {
 	// 先把请求加入请求池中
 	ch := connActor.requestHolder.Put(requstID)
@@ -180,6 +187,19 @@ func (connActor *ConnActor) RequestAndReponse(b []byte,
 		return resp, nil
 	case <-connActor.ctx.Done():
 		return nil, ErrClosed
+	case <-ctx.Done():
+		connActor.requestHolder.Delete(requstID)
+		// 响应可能正在写入，接收掉避免阻塞读取goroutine
+		go func() {
+			select {
+			case <-ch:
+			case <-time.After(cell):
+			}
+		}()
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, ErrTimeout
+		}
+		return nil, ctx.Err()
 	}
 }
 
@@ -195,7 +215,7 @@ func (connActor *ConnActor) Close() {
 			connActor.c.Close()
 		}
 	})
-	log.Debugf("close connactor. %s-%s",
+	connLog.Debugf("close connactor. %s-%s",
 		connActor.c.LocalAddr().String(), connActor.RemoteAddr())
 }
 
@@ -215,7 +235,7 @@ func (connActor *ConnActor) reDial() bool {
 		return false
 	}
 
-	log.Warnf("Try to redial to:%s", connActor.RemoteAddr())
+	connLog.Warnf("Try to redial to:%s", connActor.RemoteAddr())
 	var tempDelay = 5 * time.Millisecond
 	for {
 		conn, err := net.Dial("tcp", connActor.c.RemoteAddr().String())
@@ -223,12 +243,12 @@ func (connActor *ConnActor) reDial() bool {
 			connActor.c = conn
 			go ContinuousRecvPack(connActor.c, connActor.processInComing)
 			atomic.StoreInt32(&connActor.status, CA_OK)
-			log.Debugf("reDial successed. %s-%s", connActor.c.LocalAddr().String(), connActor.RemoteAddr())
+			connLog.Debugf("reDial successed. %s-%s", connActor.c.LocalAddr().String(), connActor.RemoteAddr())
 			return true
 		}
 		if tempDelay > time.Second {
 			atomic.StoreInt32(&connActor.status, CA_ABANDON)
-			log.Error("reDial failed!")
+			connLog.Error("reDial failed!")
 			connActor.Close()
 			return false
 		}
@@ -240,7 +260,7 @@ func (connActor *ConnActor) reDial() bool {
 
 func (connActor *ConnActor) processInComing(packbuf []byte) {
 	if packbuf == nil { // 连接断开
-		log.Warnf("Learn about connection broken. %s-%s",
+		connLog.Warnf("Learn about connection broken. %s-%s",
 			connActor.c.LocalAddr().String(), connActor.RemoteAddr())
 		atomic.StoreInt32(&connActor.status, CA_BROKEN)
 		if !connActor.reconn {
@@ -260,46 +280,48 @@ func (connActor *ConnActor) processInComing(packbuf []byte) {
 	case passiveConnActor:
 		var r protocol.Proto
 		if err := r.UnSerialize(packbuf); err != nil {
-			log.Errorf("receive bad pack,unserialize fail,detail=%s", err.Error())
+			connLog.Errorf("receive bad pack,unserialize fail,detail=%s", err.Error())
 			return
 		}
+		// 处理方法外的panic也不能导致进程退出，没有写回响应时返回500
+		var written bool
+		defer func() {
+			if rec := recover(); rec != nil {
+				pe := RecoverPanic(rec, &r)
+				if written {
+					return
+				}
+				resp := r.Shadow()
+				fillError(&resp, errStatus(pe), pe)
+				b, _ := resp.Serialize()
+				connActor.Write(b)
+			}
+		}()
 
+		var start = time.Now()
 		var w = r.Shadow()
 		c := connActor.p.Get().(*icecontext)
 		c.Reset(&r, &w)
+		span := SpanFromTask(&r)
+		c.ctx = opentracing.ContextWithSpan(c.ctx, span)
 		var s = Instance()
+		var err error
 		if sd := s.getMethod(r.GetServeMethod()); sd == nil {
-			c.Response().FillErrInfo(http.StatusNotFound, ErrMethodNotFound)
-		} else {
-			log.Info(r.AsString())
-			for i := range s.prepare {
-				if err := s.prepare[i](c); err != nil {
-					c.Response().FillErrInfo(
-						http.StatusInternalServerError, err)
-					goto REPLY
-				}
-			}
-
-			if err := sd.Handler(s.service, c); err != nil {
-				c.Response().FillErrInfo(
-					http.StatusInternalServerError, err)
-			} else if len(c.Response().GetBody()) == 0 {
-				c.JSON2(0, "success", nil)
-			}
-
-			for i := range s.after {
-				if err := s.after[i](c); err != nil {
-					c.Response().FillErrInfo(
-						http.StatusInternalServerError, err)
-					c.Response().Body = nil
-				}
-			}
-		REPLY:
-			log.Info(c.Response().AsString())
+			fillError(c.Response(), http.StatusNotFound, ErrMethodNotFound)
+		} else if c.caller, err = s.authorize(&r, connActor.RemoteAddr()); err != nil {
+			fillError(c.Response(), http.StatusForbidden, err)
+		} else if err := s.serve(c, sd); err != nil {
+			fillError(c.Response(), errStatus(err), err)
+		} else if len(c.Response().GetBody()) == 0 && c.Response().GetStatus() == 0 {
+			c.JSON2(0, "success", nil)
 		}
-		connActor.p.Put(c)
+		FinishSpan(span, respStatus(c.Response()), nil)
+		s.access.Log(&r, c.Response(), 0, time.Since(start))
 		// 写回响应数据
 		b, _ := c.Response().Serialize()
+		c.release()
+		connActor.p.Put(c)
+		written = true
 		connActor.Write(b)
 	case activeConnActor:
 		connActor.requestHolder.Incoming(packbuf, connActor)
@@ -7,8 +7,6 @@ import (
 	"math"
 	"sort"
 	"sync"
-
-	log "github.com/kwins/iceberg/frame/icelog"
 )
 
 /*
@@ -25,7 +23,7 @@ func (h *_NodeListSeq) Insert(x interface{}) {
 	// 这样就不用每次插入都做一次排序了
 	*h = append(*h, x.(uint32))
 	sort.Sort(h)
-	log.Debug("insert node successful.")
+	routeLog.Debug("insert node successful.")
 }
 
 func (h *_NodeListSeq) Remove(x interface{}) bool {
@@ -35,7 +33,7 @@ func (h *_NodeListSeq) Remove(x interface{}) bool {
 
 	i := sort.Search(h.Len(), func(i int) bool { return (*h)[i] >= x.(uint32) })
 	if i < h.Len() && (*h)[i] == x.(uint32) {
-		log.Debugf("remove node from nodeList:%d len:%d", i, h.Len())
+		routeLog.Debugf("remove node from nodeList:%d len:%d", i, h.Len())
 		*h = append((*h)[:i], (*h)[i+1:]...)
 		return true
 	} else {
@@ -79,7 +77,7 @@ type ConsistentHash struct {
 // Leastload 返回服务实例中负载最小的节点
 func (chash *ConsistentHash) Leastload() string {
 	if len(chash.ring) == 0 {
-		log.Warn("connsistent hash circle is nil")
+		routeLog.Warn("connsistent hash circle is nil")
 		return ""
 	}
 
@@ -103,10 +101,57 @@ func (chash *ConsistentHash) Leastload() string {
 	return remodeAddr
 }
 
+// LeastloadFunc 返回满足accept条件的服务实例中负载最小的节点
+// 没有满足条件的节点时返回空;accept可能获取其他锁，在锁外执行
+func (chash *ConsistentHash) LeastloadFunc(accept func(remoteAddr string) bool) string {
+	return chash.LeastloadWeight(accept, nil)
+}
+
+// LeastloadWeight 返回满足accept条件的服务实例中按权重计算负载最小的节点
+// accept为nil表示不过滤，weight为nil或返回值不大于0时权重按1计算;
+// 负载为 (已分配请求数+1)/权重，请求按权重比例分配到各节点
+func (chash *ConsistentHash) LeastloadWeight(accept func(remoteAddr string) bool, weight func(remoteAddr string) int) string {
+	type candidate struct {
+		node  *Node
+		reqNo int64
+	}
+	chash.RLock()
+	candidates := make([]candidate, 0, len(chash.ring))
+	for _, node := range chash.ring {
+		candidates = append(candidates, candidate{node: node, reqNo: node.reqNo})
+	}
+	chash.RUnlock()
+
+	var selected *Node
+	var minmum = math.MaxFloat64
+	for _, c := range candidates {
+		if accept != nil && !accept(c.node.remoteAddr) {
+			continue
+		}
+		w := 1
+		if weight != nil {
+			if w = weight(c.node.remoteAddr); w <= 0 {
+				w = 1
+			}
+		}
+		if load := float64(c.reqNo+1) / float64(w); load < minmum {
+			minmum = load
+			selected = c.node
+		}
+	}
+	if selected == nil {
+		return ""
+	}
+	chash.Lock()
+	selected.reqNo++
+	chash.Unlock()
+	return selected.remoteAddr
+}
+
 // Find find node
 func (chash *ConsistentHash) Find(key []byte) *Node {
 	if len(chash.nodeList) == 0 {
-		log.Warn("The ring is empty!")
+		routeLog.Warn("The ring is empty!")
 		return nil
 	}
 	return chash.find(key)
@@ -146,7 +191,7 @@ func (chash *ConsistentHash) AddNode(svrAddr string) bool {
 	hashed := _hash([]byte(svrAddr))
 
 	if v, found := chash.ring[hashed]; found {
-		log.Warnf("Hash crash, chash node [%s:%d] is existed in ring [%s:%d]", svrAddr, hashed, v.remoteAddr, hashed)
+		routeLog.Warnf("Hash crash, chash node [%s:%d] is existed in ring [%s:%d]", svrAddr, hashed, v.remoteAddr, hashed)
 		return false
 	}
 
@@ -156,7 +201,7 @@ func (chash *ConsistentHash) AddNode(svrAddr string) bool {
 	chash.ring[hashed] = node
 	chash.Unlock()
 	chash.nodeList.Insert(hashed)
-	log.Debugf("Add a new node %s into hash ring,hashed=%d", svrAddr, hashed)
+	routeLog.Debugf("Add a new node %s into hash ring,hashed=%d", svrAddr, hashed)
 
 	return true
 }
@@ -170,7 +215,7 @@ func (chash *ConsistentHash) RmNode(key []byte) string {
 	v := _hash(key)
 	l, found := chash.ring[v]
 	if !found {
-		log.Warn("Can't remove node, because the node is not exist.")
+		routeLog.Warn("Can't remove node, because the node is not exist.")
 		if chash.nodeList.Len() > 0 {
 			return chash.ring[chash.nodeList[0]].remoteAddr
 		} else {
@@ -180,7 +225,7 @@ func (chash *ConsistentHash) RmNode(key []byte) string {
 
 	// 在有序的节点key中找出该节点key的下标
 	if !chash.nodeList.Remove(v) {
-		log.Warn("The node is not exist in nodelist, but exist in ring, Data is not consistent!!!")
+		routeLog.Warn("The node is not exist in nodelist, but exist in ring, Data is not consistent!!!")
 	}
 	delete(chash.ring, v) // 从ring中删除节点
 
@@ -211,7 +256,7 @@ func (chash *ConsistentHash) RmNode(key []byte) string {
 Clear 清除所有节点
 */
 func (chash *ConsistentHash) Clear() {
-	// log.Info("Clear nodeList.")
+	// routeLog.Info("Clear nodeList.")
 
 	chash.ring = make(map[uint32]*Node)
 	chash.nodeList = _NodeListSeq{}
@@ -240,7 +285,7 @@ func _hash(key []byte) uint32 {
 	buf := bytes.NewBuffer(result)
 	err := binary.Read(buf, binary.LittleEndian, &value)
 	if err != nil {
-		log.Error("Calculate hash failed!")
+		routeLog.Error("Calculate hash failed!")
 	}
 
 	return value
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 )
 
 var chash = NewConsistentHash()
@@ -37,3 +38,45 @@ func BenchmarkHash(b *testing.B) {
 		_hash([]byte{byte(index)})
 	}
 }
+
+func TestLeastloadFuncOutsideLock(t *testing.T) {
+	ch := NewConsistentHash()
+	ch.AddNode("127.0.0.1:1")
+	ch.AddNode("127.0.0.1:2")
+	done := make(chan string, 1)
+	go func() {
+		// accept中获取节点的锁不能死锁
+		done <- ch.LeastloadFunc(func(addr string) bool {
+			ch.Lock()
+			defer ch.Unlock()
+			return addr == "127.0.0.1:2"
+		})
+	}()
+	select {
+	case addr := <-done:
+		if addr != "127.0.0.1:2" {
+			t.Errorf("want 127.0.0.1:2,got %s", addr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("accept called with lock held")
+	}
+}
+
+func TestLeastloadWeight(t *testing.T) {
+	ch := NewConsistentHash()
+	ch.AddNode("127.0.0.1:1")
+	ch.AddNode("127.0.0.1:2")
+	weight := func(addr string) int {
+		if addr == "127.0.0.1:1" {
+			return 300
+		}
+		return 100
+	}
+	cnt := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		cnt[ch.LeastloadWeight(nil, weight)]++
+	}
+	if cnt["127.0.0.1:1"] != 300 || cnt["127.0.0.1:2"] != 100 {
+		t.Errorf("weighted pick fail,%v", cnt)
+	}
+}
@@ -4,14 +4,17 @@ import (
 	goctx "context"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/kwins/iceberg/frame/icelog"
 	"github.com/kwins/iceberg/frame/protocol"
+	"github.com/kwins/iceberg/frame/validate"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -19,12 +22,15 @@ import (
 // Context 请求上下文
 // Context包含了请求的所有信息，并封装了一系列所需的操作
 type Context interface {
-	// Context
+	// Ctx 携带请求超时时间，取消信号，链路追踪Span和Set设置的键值对的go context，请求处理完成后取消
 	Ctx() goctx.Context
 
 	// Bizid 服务全局ID
 	Bizid() string
 
+	// Logger 绑定了请求信息(bizid,request_id,uri,method)的结构化日志
+	Logger() *log.Logger
+
 	// Reset Reset
 	Reset(r *protocol.Proto, w *protocol.Proto)
 
@@ -35,6 +41,7 @@ type Context interface {
 	Response() *protocol.Proto
 
 	// Bind Parse Request data
+	// 结构体可以通过form，header tag从表单和Header取值
 	Bind(i interface{}) error
 
 	// HTTP Header
@@ -49,12 +56,21 @@ type Context interface {
 	// Client Request RealIP
 	RealIP() string
 
+	// Caller 调用方服务名称，没有通过身份校验时为空
+	Caller() string
+
 	// http 表单数据和raw query都使用此结构获取看k,v对
 	FormValue(name string) string
 
 	// FormValues FormValues
 	FormValues() url.Values
 
+	// FormFile multipart表单中上传的文件，同一字段有多个文件时返回第一个，没有时返回ErrMissingFile
+	FormFile(name string) (*protocol.FormFile, error)
+
+	// FormFiles multipart表单中字段上传的所有文件
+	FormFiles(name string) []*protocol.FormFile
+
 	// GetString 获取FormValue的值
 	GetString(name string, defaultValue string) string
 
@@ -67,12 +83,45 @@ type Context interface {
 	// GetFloat 获取FormValue值并转化为 float64
 	GetFloat(name string, defaultValue float64) float64
 
-	// Ctx Get
+	// Get 获取请求级的值
 	Get(key string) interface{}
 
-	// Ctx Set
+	// Set 设置请求级的值，只在本次请求内有效
 	Set(key string, val interface{})
 
+	// Value 获取请求级的值，ok表示是否存在
+	Value(key string) (val interface{}, ok bool)
+
+	// ValueString 获取请求级的string值，不存在或类型不符时返回空
+	ValueString(key string) string
+
+	// ValueInt 获取请求级的int值，不存在或类型不符时返回0
+	ValueInt(key string) int
+
+	// ValueInt64 获取请求级的int64值，不存在或类型不符时返回0
+	ValueInt64(key string) int64
+
+	// ValueBool 获取请求级的bool值，不存在或类型不符时返回false
+	ValueBool(key string) bool
+
+	// Baggage 获取沿调用链传递的值
+	Baggage(key string) string
+
+	// SetBaggage 设置沿调用链传递的值，使用该Context发起的下游请求都会带上，val为空表示删除
+	SetBaggage(key, val string)
+
+	// Status 设置响应的HTTP状态码
+	Status(code int)
+
+	// AddHeader 添加响应Header，同名Header可以有多个值
+	AddHeader(key, val string)
+
+	// SetCookie 添加响应的Set-Cookie Header
+	SetCookie(cookie *http.Cookie)
+
+	// Redirect 重定向到location，code为3xx状态码
+	Redirect(code int, location string) error
+
 	// JSON 响应JSON数据
 	JSON(i interface{}) error
 
@@ -134,16 +183,49 @@ type icecontext struct {
 	dstFormat protocol.RestfulFormat
 	form      url.Values
 	clientip  string
+	caller    string
 	ctx       goctx.Context
+	cancel    goctx.CancelFunc
+	vals      *values
+	logger    *log.Logger
 }
 
 // Ctx 将一些需要的参数传递给下一个请求的Context
-// 多层级调用链，使用go context将bizid传递下去
+// 多层级调用链，使用go context将bizid，超时时间，Span和baggage传递下去
 func (c *icecontext) Ctx() goctx.Context {
 	if c.ctx == nil {
-		c.ctx = goctx.TODO()
+		c.ctx = goctx.Background()
+	}
+	return valuesCtx{Context: c.ctx, vals: c.values()}
+}
+
+func (c *icecontext) values() *values {
+	if c.vals == nil {
+		c.vals = newValues(c.req)
+	}
+	return c.vals
+}
+
+// withTimeout 为Ctx()设置更短的超时时间，返回的函数恢复原来的Ctx()
+func (c *icecontext) withTimeout(d time.Duration) goctx.CancelFunc {
+	parent := c.ctx
+	if parent == nil {
+		parent = goctx.Background()
+	}
+	ctx, cancel := goctx.WithTimeout(parent, d)
+	c.ctx = ctx
+	return func() {
+		cancel()
+		c.ctx = parent
+	}
+}
+
+// release 请求处理完成，取消Ctx()
+func (c *icecontext) release() {
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
 	}
-	return goctx.WithValue(c.ctx, "bizid", c.Bizid())
 }
 
 // Bizid 用户追踪ID
@@ -154,34 +236,47 @@ func (c *icecontext) Bizid() string {
 	return ""
 }
 
+// Logger 绑定了请求信息的日志
+func (c *icecontext) Logger() *log.Logger {
+	if c.logger == nil {
+		c.logger = log.With(
+			log.String("bizid", c.Bizid()),
+			log.Int64("request_id", c.req.GetRequestID()),
+			log.String("uri", c.req.GetServeURI()),
+			log.String("method", c.req.GetServeMethod()))
+		if debugRequest(c.req) {
+			c.logger = c.logger.WithDebug()
+		}
+	}
+	return c.logger
+}
+
 // Reset 调用其他方法前已在框架中Reset，故其他方法获取参数是安全的
 func (c *icecontext) Reset(r *protocol.Proto, w *protocol.Proto) {
 	c.req = r
 	c.resp = w
+	c.logger = nil
 	c.srcFormat = r.GetFormat()
 	c.dstFormat = protocol.RestfulFormat_FORMATNULL
 
-	c.header = make(http.Header)
-	for k, v := range r.GetHeader() {
-		c.header.Set(k, v)
-	}
-
-	c.form = make(url.Values)
-	for k, v := range r.GetForm() {
-		c.form.Set(k, v)
-	}
+	c.header = r.HTTPHeader()
+	c.form = r.URLValues()
 
 	c.clientip = ""
-	c.ctx = goctx.TODO()
+	c.caller = ""
+	c.release()
+	c.vals = newValues(r)
+	if d := requestTimeout(r); d > 0 {
+		c.ctx, c.cancel = goctx.WithTimeout(goctx.Background(), d)
+	} else {
+		c.ctx, c.cancel = goctx.WithCancel(goctx.Background())
+	}
 }
 
 // Header HTTP header
 func (c *icecontext) Header() http.Header {
 	if c.header == nil {
-		c.header = make(http.Header)
-		for k, v := range c.req.GetHeader() {
-			c.header.Set(k, v)
-		}
+		c.header = c.req.HTTPHeader()
 	}
 	return c.header
 }
@@ -197,9 +292,26 @@ func (c *icecontext) Response() *protocol.Proto {
 }
 
 // Bind Parse Request data
+// RAWQUERY和没有请求体的请求从表单和Header绑定结构体，其他请求解析请求体后绑定声明了form，header tag的字段，见bindValues;
+// 解析后校验请求参数，i实现了validate.Validator时校验失败返回validate.Errors
 func (c *icecontext) Bind(i interface{}) error {
-	return protocol.Unpack(c.Request().GetFormat(),
-		c.Request().GetBody(), i)
+	format := c.Request().GetFormat()
+	switch {
+	case (format == protocol.RestfulFormat_RAWQUERY || format == protocol.RestfulFormat_FORMATNULL) && isStructPtr(i):
+		if err := bindValues(i, c.form, c.Header(), true); err != nil {
+			return err
+		}
+	default:
+		if err := protocol.Unpack(format, c.Request().GetBody(), i); err != nil {
+			return err
+		}
+		if isStructPtr(i) {
+			if err := bindValues(i, c.form, c.Header(), false); err != nil {
+				return err
+			}
+		}
+	}
+	return validate.Check(i)
 }
 
 // ReqFormat 请求数据序列化格式
@@ -275,6 +387,27 @@ func (c *icecontext) FormValues() url.Values {
 	return c.form
 }
 
+// FormFile multipart表单中上传的文件
+func (c *icecontext) FormFile(name string) (*protocol.FormFile, error) {
+	for _, f := range c.Request().GetFiles() {
+		if f.GetField() == name {
+			return f, nil
+		}
+	}
+	return nil, ErrMissingFile
+}
+
+// FormFiles multipart表单中字段上传的所有文件
+func (c *icecontext) FormFiles(name string) []*protocol.FormFile {
+	var files []*protocol.FormFile
+	for _, f := range c.Request().GetFiles() {
+		if f.GetField() == name {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
 // RealIP Client Request RealIP
 func (c *icecontext) RealIP() string {
 	if c.clientip == "" {
@@ -292,20 +425,96 @@ func (c *icecontext) RealIP() string {
 	return c.clientip
 }
 
-// Get Ctx Get
+// Caller 调用方服务名称
+func (c *icecontext) Caller() string {
+	return c.caller
+}
+
+// Get 获取请求级的值
 func (c *icecontext) Get(key string) interface{} {
-	if c.ctx == nil {
-		return nil
-	}
-	return c.ctx.Value(key)
+	v, _ := c.values().get(key)
+	return v
 }
 
-// Set Ctx Set
+// Set 设置请求级的值
 func (c *icecontext) Set(key string, val interface{}) {
-	if c.ctx == nil {
-		c.ctx = goctx.TODO()
+	c.values().set(key, val)
+}
+
+// Value 获取请求级的值
+func (c *icecontext) Value(key string) (interface{}, bool) {
+	return c.values().get(key)
+}
+
+// ValueString 获取请求级的string值
+func (c *icecontext) ValueString(key string) string {
+	v, _ := c.Get(key).(string)
+	return v
+}
+
+// ValueInt 获取请求级的int值
+func (c *icecontext) ValueInt(key string) int {
+	v, _ := c.Get(key).(int)
+	return v
+}
+
+// ValueInt64 获取请求级的int64值
+func (c *icecontext) ValueInt64(key string) int64 {
+	v, _ := c.Get(key).(int64)
+	return v
+}
+
+// ValueBool 获取请求级的bool值
+func (c *icecontext) ValueBool(key string) bool {
+	v, _ := c.Get(key).(bool)
+	return v
+}
+
+// Baggage 获取沿调用链传递的值
+func (c *icecontext) Baggage(key string) string {
+	return c.values().getBaggage(key)
+}
+
+// SetBaggage 设置沿调用链传递的值
+func (c *icecontext) SetBaggage(key, val string) {
+	c.values().setBaggage(key, val)
+}
+
+// response 响应信息，没有时创建
+func (c *icecontext) response() *protocol.Proto {
+	if c.resp == nil {
+		s := c.Request().Shadow()
+		c.resp = &s
 	}
-	goctx.WithValue(c.ctx, key, val)
+	return c.resp
+}
+
+// Status 设置响应的HTTP状态码
+func (c *icecontext) Status(code int) {
+	c.response().Status = int32(code)
+}
+
+// AddHeader 添加响应Header
+func (c *icecontext) AddHeader(key, val string) {
+	c.response().AddHeader(key, val)
+}
+
+// SetCookie 添加响应的Set-Cookie Header
+func (c *icecontext) SetCookie(cookie *http.Cookie) {
+	if v := cookie.String(); v != "" {
+		c.AddHeader("Set-Cookie", v)
+	}
+}
+
+// Redirect 重定向到location
+func (c *icecontext) Redirect(code int, location string) error {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		return fmt.Errorf("invalid redirect code %d", code)
+	}
+	resp := c.response()
+	resp.Status = int32(code)
+	resp.SetHeader("Location", location)
+	return nil
 }
 
 // JSON 响应JSON数据
@@ -398,72 +607,54 @@ func (c *icecontext) XML2(code int, msg string, data interface{}) error {
 	})
 }
 
-func (c *icecontext) appendBiz(args []interface{}) []interface{} {
-	var newargs = make([]interface{}, len(args)+1)
-	newargs[0] = "Bizid:" + c.Bizid() + " "
-	for i := range args {
-		newargs[i+1] = args[i]
-	}
-	return newargs
-}
-
-func (c *icecontext) appendf(fmt string, args []interface{}) (string, []interface{}) {
-	return "%s " + fmt, c.appendBiz(args)
-}
-
 // Debug global debug
 func (c *icecontext) Debug(args ...interface{}) {
-	log.Default().FormatAndOutput(4, log.DEBUG, "", c.appendBiz(args)...)
+	c.Logger().WithCallerSkip(1).Debug(args...)
 }
 
 // Warn defalut warn
 func (c *icecontext) Warn(args ...interface{}) {
-	log.Default().FormatAndOutput(4, log.WARNING, "", c.appendBiz(args)...)
+	c.Logger().WithCallerSkip(1).Warn(args...)
 }
 
 // Info default info
 func (c *icecontext) Info(args ...interface{}) {
-	log.Default().FormatAndOutput(4, log.INFO, "", c.appendBiz(args)...)
+	c.Logger().WithCallerSkip(1).Info(args...)
 }
 
 // Error default error
 func (c *icecontext) Error(args ...interface{}) {
-	log.Default().FormatAndOutput(4, log.ERROR, "", c.appendBiz(args)...)
+	c.Logger().WithCallerSkip(1).Error(args...)
 }
 
 // Fatal default fatal
 func (c *icecontext) Fatal(args ...interface{}) {
-	log.Default().FormatAndOutput(4, log.FATAL, "", c.appendBiz(args)...)
+	c.Logger().WithCallerSkip(1).Fatal(args...)
 }
 
 // Debugf global debug
 func (c *icecontext) Debugf(fmt string, args ...interface{}) {
-	fmt, args = c.appendf(fmt, args)
-	log.Default().FormatAndOutput(4, log.DEBUG, fmt, args...)
+	c.Logger().WithCallerSkip(1).Debugf(fmt, args...)
 }
 
 // Warnf defalut wawrn
 func (c *icecontext) Warnf(fmt string, args ...interface{}) {
-	fmt, args = c.appendf(fmt, args)
-	log.Default().FormatAndOutput(4, log.WARNING, fmt, args...)
+	c.Logger().WithCallerSkip(1).Warnf(fmt, args...)
 }
 
 // Infof default info
 func (c *icecontext) Infof(fmt string, args ...interface{}) {
-	fmt, args = c.appendf(fmt, args)
-	log.Default().FormatAndOutput(4, log.INFO, fmt, args...)
+	c.Logger().WithCallerSkip(1).Infof(fmt, args...)
 }
 
 // Errorf default error
 func (c *icecontext) Errorf(fmt string, args ...interface{}) {
-	fmt, args = c.appendf(fmt, args)
-	log.Default().FormatAndOutput(4, log.ERROR, fmt, args...)
+	c.Logger().WithCallerSkip(1).Errorf(fmt, args...)
 }
 
 // Fatalf default fatal
 func (c *icecontext) Fatalf(fmt string, args ...interface{}) {
-	fmt, args = c.appendf(fmt, args)
-	log.Default().FormatAndOutput(4, log.FATAL, fmt, args...)
+	c.Logger().WithCallerSkip(1).Fatalf(fmt, args...)
 }
 
 // TODO 默认
@@ -1,5 +1,139 @@
 package frame
 
+import (
+	goctx "context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+func TestContextValues(t *testing.T) {
+	c := NewContext().(*icecontext)
+	c.Reset(&protocol.Proto{Bizid: "b1", Header: map[string]string{
+		protocol.HeaderXIcebergTimeout: "1000",
+		"X-Iceberg-Baggage-Tenant":     "t1",
+	}}, &protocol.Proto{})
+
+	c.Set("uid", int64(10))
+	c.Set("name", "kwins")
+	ctx := c.Ctx()
+	if c.ValueInt64("uid") != 10 || c.ValueString("name") != "kwins" || c.ValueInt("name") != 0 {
+		t.Errorf("typed values fail")
+	}
+	if ctx.Value("name") != "kwins" || BizidFromContext(ctx) != "b1" {
+		t.Errorf("ctx should carry values and bizid")
+	}
+	if d, ok := ctx.Deadline(); !ok || time.Until(d) > time.Second {
+		t.Errorf("ctx should carry deadline,%v", d)
+	}
+	if c.Baggage("tenant") != "t1" {
+		t.Errorf("baggage got %s", c.Baggage("tenant"))
+	}
+
+	c.SetBaggage("region", "sh")
+	task, err := ReadyTask(c, "echo", "echo", "v1", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Header["X-Iceberg-Baggage-tenant"] != "t1" || task.Header["X-Iceberg-Baggage-region"] != "sh" {
+		t.Errorf("baggage should be propagated,%v", task.Header)
+	}
+	if d := requestTimeout(task); d <= 0 || d > time.Second+time.Millisecond {
+		t.Errorf("timeout should be propagated,%v", d)
+	}
+
+	// 复用后之前的ctx被取消，且不会读到新请求的值
+	c.Reset(&protocol.Proto{Bizid: "b2"}, &protocol.Proto{})
+	c.Set("name", "other")
+	if ctx.Err() != goctx.Canceled {
+		t.Errorf("old ctx should be canceled,%v", ctx.Err())
+	}
+	if ctx.Value("name") != "kwins" || c.ValueInt64("uid") != 0 {
+		t.Errorf("values leaked between requests")
+	}
+	if _, err := ReadyTask(c, "echo", "echo", "v1", nil, WithContext(ctx)); err != goctx.Canceled {
+		t.Errorf("canceled ctx should fail the call,%v", err)
+	}
+}
+
+func TestContextFormFile(t *testing.T) {
+	c := NewContext()
+	c.Reset(&protocol.Proto{Files: []*protocol.FormFile{
+		{Field: "avatar", Filename: "a.png", Content: []byte("a")},
+		{Field: "photos", Filename: "1.png"},
+		{Field: "photos", Filename: "2.png"},
+	}}, &protocol.Proto{})
+	if f, err := c.FormFile("avatar"); err != nil || f.Filename != "a.png" {
+		t.Errorf("form file fail,%v,%v", f, err)
+	}
+	if _, err := c.FormFile("none"); err != ErrMissingFile {
+		t.Errorf("missing file should fail,%v", err)
+	}
+	if files := c.FormFiles("photos"); len(files) != 2 || files[1].Filename != "2.png" {
+		t.Errorf("form files fail,%v", files)
+	}
+}
+
+func TestContextResponse(t *testing.T) {
+	c := NewContext()
+	c.Reset(&protocol.Proto{}, &protocol.Proto{})
+	c.SetCookie(&http.Cookie{Name: "a", Value: "1"})
+	c.SetCookie(&http.Cookie{Name: "b", Value: "2"})
+	if err := c.Redirect(http.StatusOK, "/"); err == nil {
+		t.Error("redirect should require 3xx")
+	}
+	if err := c.Redirect(http.StatusFound, "/login"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.Response().Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp protocol.Proto
+	if err := resp.UnSerialize(b); err != nil {
+		t.Fatal(err)
+	}
+	h := resp.HTTPHeader()
+	if resp.GetStatus() != http.StatusFound || h.Get("Location") != "/login" || len(h["Set-Cookie"]) != 2 {
+		t.Errorf("response fail,status=%d header=%v", resp.GetStatus(), h)
+	}
+	if respStatus(&resp) != http.StatusFound {
+		t.Errorf("access log status should be %d", http.StatusFound)
+	}
+}
+
+func TestContextMultiValues(t *testing.T) {
+	var req protocol.Proto
+	req.SetURLValues(url.Values{"id": {"1", "2"}})
+	req.SetHTTPHeader(http.Header{"X-Tag": {"a", "b"}})
+	c := NewContext()
+	c.Reset(&req, &protocol.Proto{})
+	if len(c.FormValues()["id"]) != 2 || len(c.Header()["X-Tag"]) != 2 {
+		t.Errorf("context should see all values,%v %v", c.FormValues(), c.Header())
+	}
+
+	task, err := ReadyTask(c, "echo", "echo", "v1", nil,
+		FormValues(url.Values{"id": {"3", "4"}}), Header(http.Header{"X-Tag": {"c", "d"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := task.URLValues()["id"]; len(ids) != 2 || ids[0] != "3" {
+		t.Errorf("form values option fail,%v", ids)
+	}
+	if tags := task.HTTPHeader()["X-Tag"]; len(tags) != 2 || tags[1] != "d" {
+		t.Errorf("header option fail,%v", tags)
+	}
+
+	task.SetHeader("X-Tag", "e")
+	if tags := task.HTTPHeader()["X-Tag"]; len(tags) != 1 || tags[0] != "e" {
+		t.Errorf("set header should replace all values,%v", tags)
+	}
+}
+
 // import (
 // 	"github.com/kwins/iceberg/frame/protocol"
 // 	"testing"
@@ -1,7 +1,6 @@
 package frame
 
 import (
-	log "github.com/kwins/iceberg/frame/icelog"
 	"github.com/kwins/iceberg/frame/protocol"
 	"sync"
 )
@@ -36,7 +35,7 @@ func (dispatcher *Dispatcher) Incoming(b []byte, ca *ConnActor) {
 	if ch := h.Get(resp.GetRequestID()); ch != nil {
 		ch <- &resp
 	} else {
-		log.Warnf("%s not found origin request[%d]. drop dispatcher response detail:%s",
+		connLog.Warnf("%s not found origin request[%d]. drop dispatcher response detail:%s",
 			resp.GetBizid(), resp.GetRequestID(), resp.String())
 	}
 }
@@ -10,4 +10,6 @@ var (
 	ErrClosed         = errors.New("连接关闭")
 	ErrTimeout        = errors.New("请求超时")
 	ErrMethodNotFound = errors.New("资源不存在")
+	ErrMissingFile    = errors.New("没有上传文件")
+	ErrAccessDenied   = errors.New("没有访问权限")
 )
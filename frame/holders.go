@@ -2,7 +2,6 @@ package frame
 
 import (
 	"container/list"
-	log "github.com/kwins/iceberg/frame/icelog"
 	"github.com/kwins/iceberg/frame/protocol"
 	"sync"
 	"time"
@@ -45,7 +44,7 @@ func (h *Holder) Put(reqID int64, ch chan *protocol.Proto) {
 	h.locker.Lock()
 	if _, found := h.request[reqID]; found {
 		h.locker.Unlock()
-		log.Warn("the request is existed, requestID=", reqID)
+		connLog.Warn("the request is existed, requestID=", reqID)
 		return
 	}
 
@@ -100,7 +99,7 @@ func (h *Holder) autoGc() {
 					if ch := h.request[reqid]; ch != nil {
 						close(ch)
 						delete(h.request, reqid)
-						log.Warn("delete timeout request, requestID=", reqid)
+						connLog.Warn("delete timeout request, requestID=", reqid)
 					}
 				}
 				delete(h.bulked, k)
@@ -0,0 +1,157 @@
+package icelog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entry 一条日志
+type Entry struct {
+	Time    time.Time
+	Level   int
+	Host    string
+	Service string
+	Caller  string
+	Message string
+	Fields  []Field
+}
+
+// LevelName 日志级别名称
+func LevelName(level int) string {
+	if level < DEBUG || level > FATAL {
+		return strconv.Itoa(level)
+	}
+	return strings.TrimSpace(levleFlags[level])
+}
+
+// Encoder 日志编码，返回的内容需以换行结尾
+type Encoder interface {
+	Encode(e *Entry) []byte
+}
+
+// JSONEncoder 每条日志输出为一行JSON，便于ELK等系统解析
+type JSONEncoder struct {
+	TimeLayout string // 默认RFC3339Nano
+}
+
+// Encode encode entry to json
+func (enc *JSONEncoder) Encode(e *Entry) []byte {
+	layout := enc.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeJSONField(&buf, "time", e.Time.Format(layout), true)
+	writeJSONField(&buf, "level", LevelName(e.Level), false)
+	writeJSONField(&buf, "host", e.Host, false)
+	if e.Service != "" {
+		writeJSONField(&buf, "service", e.Service, false)
+	}
+	if e.Caller != "" {
+		writeJSONField(&buf, "caller", e.Caller, false)
+	}
+	writeJSONField(&buf, "msg", e.Message, false)
+	for _, f := range e.Fields {
+		writeJSONField(&buf, f.Key, f.Value, false)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		buf.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(v)
+}
+
+// LogfmtEncoder 每条日志输出为一行 key=value
+type LogfmtEncoder struct {
+	TimeLayout string // 默认RFC3339Nano
+}
+
+// Encode encode entry to logfmt
+func (enc *LogfmtEncoder) Encode(e *Entry) []byte {
+	layout := enc.TimeLayout
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	var buf bytes.Buffer
+	writeLogfmtField(&buf, "time", e.Time.Format(layout))
+	writeLogfmtField(&buf, "level", strings.ToLower(LevelName(e.Level)))
+	writeLogfmtField(&buf, "host", e.Host)
+	if e.Service != "" {
+		writeLogfmtField(&buf, "service", e.Service)
+	}
+	if e.Caller != "" {
+		writeLogfmtField(&buf, "caller", e.Caller)
+	}
+	writeLogfmtField(&buf, "msg", e.Message)
+	for _, f := range e.Fields {
+		writeLogfmtField(&buf, f.Key, f.Value)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func writeLogfmtField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	buf.WriteString(logfmtValue(value))
+}
+
+func logfmtValue(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	case int, int32, int64, uint, uint32, uint64, float32, float64, bool:
+		return fmt.Sprint(v)
+	default:
+		if b, err := json.Marshal(v); err == nil {
+			s = string(b)
+		} else {
+			s = fmt.Sprint(v)
+		}
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// fieldsText 文本格式下附加在日志后的字段
+func fieldsText(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	for _, f := range fields {
+		writeLogfmtField(&buf, f.Key, f.Value)
+	}
+	return buf.String()
+}
@@ -0,0 +1,78 @@
+package icelog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Field 结构化日志字段
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String string field
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int int field
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int64 int64 field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Float64 float64 field
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool bool field
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Duration 时间字段，输出为 1.5ms 格式
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value.String()}
+}
+
+// Err error字段，key为error
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error", Value: nil}
+	}
+	return Field{Key: "error", Value: err.Error()}
+}
+
+// Any 任意类型字段，JSON格式下按json序列化
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// kvToFields 将 k1,v1,k2,v2 形式的参数转为字段
+// 多余的值使用 EXTRA 作为key
+func kvToFields(keysAndValues []interface{}) []Field {
+	var fields = make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if f, ok := keysAndValues[i].(Field); ok {
+			fields = append(fields, f)
+			i--
+			continue
+		}
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, Field{Key: "EXTRA", Value: keysAndValues[i]})
+			break
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		fields = append(fields, Field{Key: key, Value: keysAndValues[i+1]})
+	}
+	return fields
+}
@@ -1,88 +1,369 @@
 package icelog
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
 	"os"
-	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 var pathVariableTable map[byte]func(*time.Time) int
 
+// 日志轮转
+// 文件名可以包含时间变量 %Y(年) %M(月) %D(日) %H(时) %m(分)，时间变化时切换到新文件;
+// 文件大小超过MaxSize时，当前文件重命名为 <文件名>.1 <文件名>.2 ...，继续写入原文件名;
+// 轮转出来的文件可以使用gzip压缩，并按MaxFiles和MaxAge清理
+
+// RotateOptions 日志轮转配置
+type RotateOptions struct {
+	Pattern  string        // 追加在文件路径后的时间模式，默认 .%Y-%M-%D，路径中已包含时间变量时忽略
+	MaxSize  int64         // 单个文件最大字节数，0不限制
+	MaxFiles int           // 最多保留的轮转文件数，0不限制
+	MaxAge   time.Duration // 轮转文件最长保留时间，0不限制
+	Compress bool          // gzip压缩轮转文件
+}
+
 // FileWriter 实现Write接口
 type FileWriter struct {
-	filePath string
+	locker   sync.Mutex
+	filePath string // 带时间变量的文件路径
+	opts     RotateOptions
+	curPath  string
 	file     *os.File
-	buf      *bytes.Buffer
+	buf      *bufio.Writer
+	size     int64
+	closed   bool
+
+	pending []millTask    // 待压缩和清理的轮转文件
+	mill    chan struct{} // 通知有新的轮转文件
+	quit    chan struct{}
+	wg      sync.WaitGroup
 }
 
-// NewFileWriter new file writer
+// NewFileWriter new file writer，按天轮转，打开文件失败时返回nil
 func NewFileWriter(path string) *FileWriter {
+	fw, err := NewRotateWriter(path, RotateOptions{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "icelog:open %s fail,%s\n", path, err.Error())
+	}
+	return fw
+}
+
+// NewRotateWriter 按配置轮转的日志文件，打开文件失败时返回错误
+func NewRotateWriter(path string, opts RotateOptions) (*FileWriter, error) {
+	if opts.Pattern == "" {
+		opts.Pattern = ".%Y-%M-%D"
+	}
 	fw := new(FileWriter)
-	fw.buf = bytes.NewBuffer(nil)
+	fw.opts = opts
 	fw.filePath = path
-	fw.Rotate()
+	if !strings.Contains(path, "%") {
+		fw.filePath = path + opts.Pattern
+	}
+	fw.mill = make(chan struct{}, 1)
+	fw.quit = make(chan struct{})
+	if err := fw.rotate(time.Now()); err != nil {
+		return nil, err
+	}
 	fw.initWriter()
-	return fw
+	return fw, nil
 }
 
 func (w *FileWriter) initWriter() {
+	w.wg.Add(2)
 	go func() {
-		flushTimer := time.NewTimer(time.Millisecond * 500)
-		rotateTimer := time.NewTimer(time.Second * 10)
+		defer w.wg.Done()
+		ticker := time.NewTicker(time.Millisecond * 500)
+		defer ticker.Stop()
 		for {
 			select {
-			case <-flushTimer.C:
-				if w.file != nil {
-					w.file.Write(w.buf.Next(w.buf.Len()))
+			case now := <-ticker.C:
+				w.locker.Lock()
+				if w.buf != nil {
+					w.buf.Flush()
+				}
+				if !w.closed && w.expand(now) != w.curPath {
+					w.rotate(now)
+				}
+				w.locker.Unlock()
+			case <-w.quit:
+				return
+			}
+		}
+	}()
+	go func() {
+		defer w.wg.Done()
+		for range w.mill {
+			w.locker.Lock()
+			tasks := w.pending
+			w.pending = nil
+			w.locker.Unlock()
+			for _, task := range tasks {
+				if w.opts.Compress {
+					compress(task.rotated)
 				}
-				flushTimer.Reset(time.Millisecond * 500)
-			case <-rotateTimer.C:
-				w.Rotate()
-				rotateTimer.Reset(time.Second * 10)
+				w.clean(task.current)
 			}
 		}
 	}()
 }
 
+// addMill 添加待压缩和清理的轮转文件，不阻塞写日志，调用方需持有锁
+func (w *FileWriter) addMill(task millTask) {
+	w.pending = append(w.pending, task)
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+}
+
 func (w *FileWriter) Write(text string) error {
-	_, err := w.buf.WriteString(text)
+	w.locker.Lock()
+	defer w.locker.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.buf == nil {
+		// 上次轮转失败，重新打开文件
+		if err := w.rotate(time.Now()); err != nil {
+			return err
+		}
+	}
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(text)) > w.opts.MaxSize {
+		if err := w.rotateSize(); err != nil {
+			if w.buf == nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "icelog:rotate %s fail,%s\n", w.curPath, err.Error())
+		}
+	}
+	n, err := w.buf.WriteString(text)
+	w.size += int64(n)
 	return err
 }
 
 // Rotate Rotate
 func (w *FileWriter) Rotate() error {
-	oldFile := w.file
+	w.locker.Lock()
+	defer w.locker.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate(time.Now())
+}
 
-	if err := os.MkdirAll(path.Dir(w.filePath), 0755); err != nil {
-		if !os.IsExist(err) {
-			return err
-		}
+// rotate 打开当前时间对应的文件，调用方需持有锁
+func (w *FileWriter) rotate(now time.Time) error {
+	path := w.expand(now)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
 	}
 
-	path := w.filePath + "." + time.Now().Format("2006-01-02")
+	oldPath := w.curPath
+	w.closeFile()
+	w.file, w.curPath, w.size = f, path, size
+	w.buf = bufio.NewWriterSize(f, 64*1024)
+	if oldPath != "" && oldPath != path {
+		w.addMill(millTask{rotated: oldPath, current: path})
+	}
+	return nil
+}
 
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+// rotateSize 当前文件重命名为下一个序号，调用方需持有锁
+func (w *FileWriter) rotateSize() error {
+	w.closeFile()
+	var idx = 1
+	for ; ; idx++ {
+		_, err1 := os.Stat(w.curPath + "." + strconv.Itoa(idx))
+		_, err2 := os.Stat(w.curPath + "." + strconv.Itoa(idx) + ".gz")
+		if os.IsNotExist(err1) && os.IsNotExist(err2) {
+			break
+		}
+	}
+	backup := w.curPath + "." + strconv.Itoa(idx)
+	if err := os.Rename(w.curPath, backup); err != nil {
+		// 重命名失败时继续写入当前文件
+		if rerr := w.reopen(); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+	w.curPath = ""
+	if err := w.rotate(time.Now()); err != nil {
+		return err
+	}
+	w.addMill(millTask{rotated: backup, current: w.curPath})
+	return nil
+}
+
+// reopen 重新打开当前文件，调用方需持有锁
+func (w *FileWriter) reopen() error {
+	f, err := os.OpenFile(w.curPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return err
 	}
+	var size int64
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+	w.file, w.size = f, size
+	w.buf = bufio.NewWriterSize(f, 64*1024)
+	return nil
+}
 
-	w.file = f
+func (w *FileWriter) closeFile() {
+	if w.buf != nil {
+		w.buf.Flush()
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	w.file, w.buf = nil, nil
+}
 
-	if oldFile != nil {
-		oldFile.Close()
+// Flush 将缓存写入文件
+func (w *FileWriter) Flush() error {
+	w.locker.Lock()
+	defer w.locker.Unlock()
+	if w.buf == nil {
+		return nil
 	}
-	return nil
+	return w.buf.Flush()
 }
 
 // Close close
 func (w *FileWriter) Close() error {
-	_, err := w.file.Write(w.buf.Bytes())
-	err = w.file.Close()
+	w.locker.Lock()
+	if w.closed {
+		w.locker.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.buf != nil {
+		err = w.buf.Flush()
+	}
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	w.file, w.buf = nil, nil
+	close(w.quit)
+	close(w.mill)
+	w.locker.Unlock()
+	w.wg.Wait()
 	return err
 }
 
-// TO DO 日志轮转
+// expand 替换路径中的时间变量
+func (w *FileWriter) expand(now time.Time) string {
+	var args []interface{}
+	for i := 0; i < len(w.filePath)-1; i++ {
+		if w.filePath[i] != '%' {
+			continue
+		}
+		if fn, ok := pathVariableTable[w.filePath[i+1]]; ok {
+			args = append(args, fn(&now))
+			i++
+		}
+	}
+	if len(args) == 0 {
+		return w.filePath
+	}
+	return fmt.Sprintf(convertPatternToFmt([]byte(w.filePath)), args...)
+}
+
+type millTask struct {
+	rotated string // 轮转出来的文件
+	current string // 当前正在写入的文件
+}
+
+// clean 按MaxFiles和MaxAge清理轮转文件
+func (w *FileWriter) clean(current string) {
+	if w.opts.MaxFiles <= 0 && w.opts.MaxAge <= 0 {
+		return
+	}
+	prefix := w.filePath
+	if i := strings.IndexByte(prefix, '%'); i != -1 {
+		prefix = prefix[:i]
+	}
+	dir, base := filepath.Split(prefix)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return
+	}
+
+	cur := filepath.Base(current)
+	var files []os.FileInfo
+	for _, info := range infos {
+		if info.IsDir() || info.Name() == cur || !strings.HasPrefix(info.Name(), base) {
+			continue
+		}
+		files = append(files, info)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().After(files[j].ModTime()) })
+	for i, info := range files {
+		if (w.opts.MaxFiles > 0 && i >= w.opts.MaxFiles) ||
+			(w.opts.MaxAge > 0 && time.Since(info.ModTime()) > w.opts.MaxAge) {
+			os.Remove(filepath.Join(dir, info.Name()))
+		}
+	}
+}
+
+// compress gzip压缩文件并删除原文件
+func compress(path string) {
+	if strings.HasSuffix(path, ".gz") {
+		return
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return
+	}
+	os.Remove(path)
+}
+
 func getYear(now *time.Time) int {
 	return now.Year()
 }
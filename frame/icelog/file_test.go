@@ -0,0 +1,105 @@
+package icelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	w := &FileWriter{filePath: "logs/ice.%Y%M%D%H.log"}
+	now := time.Date(2018, 3, 5, 7, 0, 0, 0, time.Local)
+	if p := w.expand(now); p != "logs/ice.2018030507.log" {
+		t.Errorf("expand got %s", p)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "icelog")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	fw, err := NewRotateWriter(filepath.Join(dir, "ice.log"), RotateOptions{
+		MaxSize:  100,
+		MaxFiles: 2,
+		Compress: true,
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				fw.Write(strings.Repeat("a", 19) + "\n")
+			}
+		}()
+	}
+	wg.Wait()
+	if err := fw.Close(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := fw.Write("closed\n"); err == nil {
+		t.Error("write after close should fail")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "ice.log.*"))
+	var gz, active int
+	for _, f := range files {
+		if strings.HasSuffix(f, ".gz") {
+			gz++
+		} else {
+			active++
+		}
+	}
+	// 当前文件 + 最多2个压缩的轮转文件
+	if active != 1 || gz != 2 {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestRotateFail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "icelog")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	// 打开失败时不返回writer
+	notDir := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(notDir, nil, 0644); err != nil {
+		t.Fatal(err.Error())
+	}
+	if fw, err := NewRotateWriter(filepath.Join(notDir, "ice.log"), RotateOptions{}); err == nil || fw != nil {
+		t.Error("open fail should return nil writer")
+	}
+
+	fw, err := NewRotateWriter(filepath.Join(dir, "ice.log"), RotateOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer fw.Close()
+	if err := fw.Write("0123456789"); err != nil {
+		t.Fatal(err.Error())
+	}
+	// 当前文件被删除，重命名失败后继续写入
+	fw.Flush()
+	os.Remove(fw.curPath)
+	for i := 0; i < 3; i++ {
+		if err := fw.Write("after\n"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	fw.Flush()
+	if b, err := ioutil.ReadFile(fw.curPath); err != nil || !strings.Contains(string(b), "after") {
+		t.Errorf("write after rotate fail,%s %v", b, err)
+	}
+}
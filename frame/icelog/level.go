@@ -0,0 +1,142 @@
+package icelog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// 运行时日志级别
+// 全局级别对所有Logger生效，命名Logger可以单独设置级别，没有设置时继承上一级名称的级别，
+// 如 frame.discover 依次查找 frame.discover，frame，最后使用全局级别;
+// WithDebug 返回的Logger忽略级别设置，用于单个请求的调试
+
+// Named 返回命名的子Logger，父Logger已命名时名称使用点号连接
+func (l *Logger) Named(name string) *Logger {
+	child := l.With()
+	if l.name != "" {
+		child.name = l.name + "." + name
+	} else {
+		child.name = name
+	}
+	return child
+}
+
+// WithDebug 返回输出所有级别日志的子Logger
+func (l *Logger) WithDebug() *Logger {
+	child := l.With()
+	child.debug = true
+	return child
+}
+
+// Name 命名Logger的名称
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// Enabled 该级别的日志是否会输出
+func (l *Logger) Enabled(level int) bool {
+	if l.debug {
+		return true
+	}
+	return level >= l.effectiveLevel()
+}
+
+func (l *Logger) effectiveLevel() int {
+	if l.name != "" {
+		l.levelLocker.RLock()
+		defer l.levelLocker.RUnlock()
+		if len(l.named) > 0 {
+			for name := l.name; name != ""; {
+				if level, ok := l.named[name]; ok {
+					return level
+				}
+				i := strings.LastIndexByte(name, '.')
+				if i == -1 {
+					break
+				}
+				name = name[:i]
+			}
+		}
+	}
+	return int(atomic.LoadInt32(&l.level))
+}
+
+// Named 返回命名Logger
+func Named(name string) *Logger {
+	return defaultLogger.Named(name)
+}
+
+// GetLevel 当前全局日志级别
+func GetLevel() string {
+	return LevelName(int(atomic.LoadInt32(&defaultLogger.level)))
+}
+
+// SetNamedLevel 设置命名Logger的级别，level为空表示删除设置，使用上一级的级别
+func SetNamedLevel(name, level string) error {
+	if name == "" {
+		if _, ok := ParseLevel(level); !ok {
+			return fmt.Errorf("unknown log level %s", level)
+		}
+		SetLevel(level)
+		return nil
+	}
+	defaultLogger.levelLocker.Lock()
+	defer defaultLogger.levelLocker.Unlock()
+	if level == "" {
+		delete(defaultLogger.named, name)
+		return nil
+	}
+	l, ok := ParseLevel(level)
+	if !ok {
+		return fmt.Errorf("unknown log level %s", level)
+	}
+	defaultLogger.named[name] = l
+	return nil
+}
+
+// Levels 当前所有的级别设置，key为空表示全局级别
+func Levels() map[string]string {
+	defaultLogger.levelLocker.RLock()
+	defer defaultLogger.levelLocker.RUnlock()
+	var levels = make(map[string]string, len(defaultLogger.named)+1)
+	levels[""] = GetLevel()
+	for name, l := range defaultLogger.named {
+		levels[name] = LevelName(l)
+	}
+	return levels
+}
+
+// SetLevels 使用配置替换所有的级别设置
+// spec 为单个级别如 info，或者JSON如 {"":"info","frame.discover":"debug"}
+func SetLevels(spec string) error {
+	spec = strings.TrimSpace(spec)
+	var levels = make(map[string]string)
+	if strings.HasPrefix(spec, "{") {
+		if err := json.Unmarshal([]byte(spec), &levels); err != nil {
+			return fmt.Errorf("bad log level spec %s,%s", spec, err.Error())
+		}
+	} else {
+		levels[""] = spec
+	}
+
+	var named = make(map[string]int, len(levels))
+	for name, level := range levels {
+		l, ok := ParseLevel(level)
+		if !ok {
+			return fmt.Errorf("unknown log level %s of %s", level, name)
+		}
+		if name == "" {
+			continue
+		}
+		named[name] = l
+	}
+	if level, ok := levels[""]; ok {
+		SetLevel(level)
+	}
+	defaultLogger.levelLocker.Lock()
+	defaultLogger.named = named
+	defaultLogger.levelLocker.Unlock()
+	return nil
+}
@@ -2,12 +2,15 @@ package icelog
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kwins/iceberg/frame/util"
@@ -41,50 +44,140 @@ var (
 	defaultLogger *Logger
 )
 
-// Logger logger
-type Logger struct {
+// core 同一个日志的所有子Logger共享输出和级别
+type core struct {
 	console  *log.Logger
+	stdout   io.Writer
 	file     *FileWriter
-	level    int
+	level    int32 // 全局级别，原子读写
 	layout   string
 	showLine bool
+	encoder  Encoder // 为空时使用文本格式
+	service  string
+	pipe     *pipeline // 不为空时日志经异步管道写入所有Sink
+
+	pipeLocker sync.RWMutex // 保护pipe，SetPipeline和AddSink可能与日志输出并发
+
+	levelLocker sync.RWMutex
+	named       map[string]int // 命名Logger的级别
+}
+
+// Logger logger
+type Logger struct {
+	*core
+	fields []Field
+	skip   int
+	name   string // 命名Logger的名称，如 frame.discover
+	debug  bool   // 忽略级别设置，输出所有日志
 }
 
 // NewLogger new logger
 func NewLogger() *Logger {
-	defaultLogger = new(Logger)
+	defaultLogger = &Logger{core: &core{named: make(map[string]int)}}
 	defaultLogger.console = log.New(os.Stdout, "", log.Ldate|log.Lmicroseconds)
-	defaultLogger.level = DEBUG
+	defaultLogger.stdout = os.Stdout
+	defaultLogger.level = int32(DEBUG)
 	defaultLogger.layout = "2006-01-02 15:04:05.999"
 	defaultLogger.showLine = true
 	return defaultLogger
 }
 
+// With 返回附加了字段的子Logger，子Logger与父Logger共享输出和级别
+func (l *Logger) With(fields ...Field) *Logger {
+	child := &Logger{core: l.core, skip: l.skip, name: l.name, debug: l.debug}
+	child.fields = make([]Field, 0, len(l.fields)+len(fields))
+	child.fields = append(child.fields, l.fields...)
+	child.fields = append(child.fields, fields...)
+	return child
+}
+
+// WithCallerSkip 返回跳过skip层调用栈记录调用位置的子Logger，用于封装Logger
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	return &Logger{core: l.core, fields: l.fields, skip: l.skip + skip, name: l.name, debug: l.debug}
+}
+
+// Fields 当前Logger附加的字段
+func (l *Logger) Fields() []Field {
+	return l.fields
+}
+
 // FormatAndOutput format and out put
 func (l *Logger) FormatAndOutput(calldepth, level int, format string, args ...interface{}) {
-	if level < l.level {
+	if !l.Enabled(level) {
 		return
 	}
-	var inf, code string
+	var inf string
 	if format != "" {
 		inf = fmt.Sprintf(format, args...)
 	} else {
 		inf = fmt.Sprint(args...)
 	}
+	l.output(calldepth, 3, level, inf, nil)
+}
+
+// output 输出日志，skip为调用位置相对output的调用栈层数
+func (l *Logger) output(calldepth, skip, level int, msg string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+	var code string
 	// source code, file and line num
 	if l.showLine {
-		_, file, line, ok := runtime.Caller(2)
+		_, file, line, ok := runtime.Caller(skip + l.skip)
 		if ok {
 			code = path.Base(file) + ":" + strconv.Itoa(line)
 		}
 	}
-	if l.file != nil {
-		l.file.Write(l.formatLog(inf, code, level))
+	if len(l.fields) > 0 || l.name != "" {
+		all := make([]Field, 0, len(l.fields)+len(fields)+1)
+		if l.name != "" {
+			all = append(all, Field{Key: "logger", Value: l.name})
+		}
+		fields = append(append(all, l.fields...), fields...)
+	}
+	// 入队时持有读锁，SetPipeline切换管道后旧管道不会再收到日志
+	l.pipeLocker.RLock()
+	defer l.pipeLocker.RUnlock()
+	pipe := l.pipe
+	if l.encoder == nil {
+		if text := fieldsText(fields); text != "" {
+			msg = msg + " " + text
+		}
+		if pipe != nil {
+			pipe.send(pipeEntry{level: level, b: []byte(fmt.Sprintf("%s %s [%s] %s %s\n",
+				hostname, time.Now().Format(l.layout), levleFlags[level], msg, code))})
+		} else if l.file != nil {
+			l.file.Write(l.formatLog(msg, code, level))
+		} else {
+			l.console.Output(calldepth, l.formatLog(msg, code, level))
+		}
+		return
+	}
+	b := l.encoder.Encode(&Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Host:    hostname,
+		Service: l.service,
+		Caller:  code,
+		Message: msg,
+		Fields:  fields,
+	})
+	if pipe != nil {
+		pipe.send(pipeEntry{level: level, b: b})
+	} else if l.file != nil {
+		l.file.Write(string(b))
 	} else {
-		l.console.Output(calldepth, l.formatLog(inf, code, level))
+		l.stdout.Write(b)
 	}
 }
 
+func (l *Logger) getPipe() *pipeline {
+	l.pipeLocker.RLock()
+	pipe := l.pipe
+	l.pipeLocker.RUnlock()
+	return pipe
+}
+
 func (l *Logger) formatLog(info, code string, level int) string {
 	if l.file != nil {
 		return fmt.Sprintf("%s %s [%s] %s\n", hostname, time.Now().Format(l.layout), levleFlags[level], info)
@@ -92,6 +185,111 @@ func (l *Logger) formatLog(info, code string, level int) string {
 	return fmt.Sprintf("%s [%s] %s %s\n", hostname, levleFlags[level], info, code)
 }
 
+// Debug debug
+func (l *Logger) Debug(args ...interface{}) {
+	l.output(2, 2, DEBUG, fmt.Sprint(args...), nil)
+}
+
+// Info info
+func (l *Logger) Info(args ...interface{}) {
+	l.output(2, 2, INFO, fmt.Sprint(args...), nil)
+}
+
+// Warn warn
+func (l *Logger) Warn(args ...interface{}) {
+	l.output(2, 2, WARNING, fmt.Sprint(args...), nil)
+}
+
+// Error error
+func (l *Logger) Error(args ...interface{}) {
+	l.output(2, 2, ERROR, fmt.Sprint(args...), nil)
+}
+
+// Fatal fatal
+func (l *Logger) Fatal(args ...interface{}) {
+	l.output(2, 2, FATAL, fmt.Sprint(args...), nil)
+}
+
+// Debugf debug
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.output(2, 2, DEBUG, fmt.Sprintf(format, args...), nil)
+}
+
+// Infof info
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.output(2, 2, INFO, fmt.Sprintf(format, args...), nil)
+}
+
+// Warnf warn
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.output(2, 2, WARNING, fmt.Sprintf(format, args...), nil)
+}
+
+// Errorf error
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.output(2, 2, ERROR, fmt.Sprintf(format, args...), nil)
+}
+
+// Fatalf fatal
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.output(2, 2, FATAL, fmt.Sprintf(format, args...), nil)
+}
+
+// Debugw 结构化日志，keysAndValues 为 k1,v1,k2,v2 或 Field
+func (l *Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.output(2, 2, DEBUG, msg, kvToFields(keysAndValues))
+}
+
+// Infow 结构化日志
+func (l *Logger) Infow(msg string, keysAndValues ...interface{}) {
+	l.output(2, 2, INFO, msg, kvToFields(keysAndValues))
+}
+
+// Warnw 结构化日志
+func (l *Logger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.output(2, 2, WARNING, msg, kvToFields(keysAndValues))
+}
+
+// Errorw 结构化日志
+func (l *Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.output(2, 2, ERROR, msg, kvToFields(keysAndValues))
+}
+
+// Fatalw 结构化日志
+func (l *Logger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.output(2, 2, FATAL, msg, kvToFields(keysAndValues))
+}
+
+// SetFormat 设置日志格式，text(默认)，json，logfmt
+func SetFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		defaultLogger.encoder = nil
+	case "json":
+		defaultLogger.encoder = new(JSONEncoder)
+	case "logfmt":
+		defaultLogger.encoder = new(LogfmtEncoder)
+	default:
+		return fmt.Errorf("not support log format %s", format)
+	}
+	return nil
+}
+
+// SetEncoder 设置自定义日志编码
+func SetEncoder(enc Encoder) {
+	defaultLogger.encoder = enc
+}
+
+// SetService 设置日志中的服务名称
+func SetService(name string) {
+	defaultLogger.service = name
+}
+
+// With 返回附加了字段的Logger
+func With(fields ...Field) *Logger {
+	return defaultLogger.With(fields...)
+}
+
 // SetLayout global SetLayout
 func SetLayout(layout string) {
 	defaultLogger.layout = layout
@@ -104,13 +302,39 @@ func SetLog(filename, level string) {
 	}
 	defaultLogger.file = NewFileWriter(filename)
 	if level != "" {
-		defaultLogger.level = levelFlagsReverse[strings.ToUpper(level)]
+		SetLevel(level)
 	}
 }
 
+// SetRotateLog 输出到按配置轮转的日志文件
+func SetRotateLog(filename, level string, opts RotateOptions) error {
+	if filename == "" {
+		filename = os.Args[0] + ".log"
+	}
+	fw, err := NewRotateWriter(filename, opts)
+	if err != nil {
+		return err
+	}
+	old := defaultLogger.file
+	defaultLogger.file = fw
+	if old != nil {
+		old.Close()
+	}
+	if level != "" {
+		SetLevel(level)
+	}
+	return nil
+}
+
+// ParseLevel 解析日志级别名称，不区分大小写
+func ParseLevel(level string) (int, bool) {
+	l, ok := levelFlagsReverse[strings.ToUpper(strings.TrimSpace(level))]
+	return l, ok
+}
+
 // SetLevel SetLevel
 func SetLevel(level string) {
-	defaultLogger.level = levelFlagsReverse[strings.ToUpper(level)]
+	atomic.StoreInt32(&defaultLogger.level, int32(levelFlagsReverse[strings.ToUpper(level)]))
 }
 
 // Debug global debug
@@ -163,13 +387,96 @@ func Fatalf(fmt string, args ...interface{}) {
 	defaultLogger.FormatAndOutput(3, FATAL, fmt, args...)
 }
 
+// Debugw 结构化日志，keysAndValues 为 k1,v1,k2,v2 或 Field
+func Debugw(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(2, 2, DEBUG, msg, kvToFields(keysAndValues))
+}
+
+// Infow 结构化日志
+func Infow(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(2, 2, INFO, msg, kvToFields(keysAndValues))
+}
+
+// Warnw 结构化日志
+func Warnw(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(2, 2, WARNING, msg, kvToFields(keysAndValues))
+}
+
+// Errorw 结构化日志
+func Errorw(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(2, 2, ERROR, msg, kvToFields(keysAndValues))
+}
+
+// Fatalw 结构化日志
+func Fatalw(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(2, 2, FATAL, msg, kvToFields(keysAndValues))
+}
+
 // Default default log
 func Default() *Logger {
 	return defaultLogger
 }
 
-// Close Close
+// SetPipeline 设置异步管道的队列长度和队列满时的策略，需要在AddSink之前调用
+func SetPipeline(queueSize, policy int) {
+	pipe := newPipeline(queueSize, policy)
+	defaultLogger.pipeLocker.Lock()
+	old := defaultLogger.pipe
+	if old != nil {
+		old.locker.RLock()
+		sinks := old.sinks
+		old.locker.RUnlock()
+		for _, s := range sinks {
+			pipe.add(s.sink, s.level)
+		}
+	}
+	defaultLogger.pipe = pipe
+	defaultLogger.pipeLocker.Unlock()
+	// 先切换再关闭，旧管道中已入队的日志写出后退出
+	if old != nil {
+		old.stop()
+	}
+}
+
+// AddSink 添加日志输出，只输出不低于level的日志
+// 添加Sink后日志不再输出到控制台和SetLog设置的文件，需要时显式添加ConsoleSink
+func AddSink(sink Sink, level int) {
+	defaultLogger.pipeLocker.Lock()
+	if defaultLogger.pipe == nil {
+		defaultLogger.pipe = newPipeline(0, PolicyBlock)
+	}
+	pipe := defaultLogger.pipe
+	defaultLogger.pipeLocker.Unlock()
+	pipe.add(sink, level)
+}
+
+// Stats 异步管道统计
+func Stats() PipeStats {
+	pipe := defaultLogger.getPipe()
+	if pipe == nil {
+		return PipeStats{}
+	}
+	return pipe.stats()
+}
+
+// Flush 等待已产生的日志全部写出
+func Flush() error {
+	if pipe := defaultLogger.getPipe(); pipe != nil {
+		if err := pipe.flush(); err != nil {
+			return err
+		}
+	}
+	if defaultLogger.file != nil {
+		return defaultLogger.file.Flush()
+	}
+	return nil
+}
+
+// Close 写出所有日志并关闭输出，程序退出前调用
 func Close() {
+	if pipe := defaultLogger.getPipe(); pipe != nil {
+		pipe.close()
+	}
 	if defaultLogger.file != nil {
 		defaultLogger.file.Close()
 	}
@@ -1,6 +1,12 @@
 package icelog
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestConsoleLog(t *testing.T) {
 	Debug("a=1", " b=2")
@@ -59,3 +65,57 @@ func TestConsoleLog(t *testing.T) {
 // 		return nil
 // 	})
 // }
+
+func TestStructuredLog(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger()
+	l.stdout = &buf
+	SetService("hello")
+	defer SetFormat("text")
+
+	SetFormat("json")
+	l.With(String("bizid", "b1")).Infow("request done", "status", 200, Duration("cost", time.Millisecond))
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err.Error(), buf.String())
+	}
+	if m["msg"] != "request done" || m["bizid"] != "b1" || m["status"] != float64(200) ||
+		m["service"] != "hello" || m["level"] != "INFO" || m["cost"] != "1ms" {
+		t.Errorf("bad json log %s", buf.String())
+	}
+	if caller, _ := m["caller"].(string); !strings.HasPrefix(caller, "log_test.go:") {
+		t.Errorf("bad caller %v", m["caller"])
+	}
+
+	buf.Reset()
+	SetFormat("logfmt")
+	l.Warnw("slow query", "sql", "select 1", "rows", 1)
+	if s := buf.String(); !strings.Contains(s, `msg="slow query" sql="select 1" rows=1`) {
+		t.Errorf("bad logfmt log %s", s)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	defer SetLevels("debug")
+	if err := SetLevels(`{"":"warn","frame":"debug","frame.discover":"error"}`); err != nil {
+		t.Fatal(err.Error())
+	}
+	if Default().Enabled(INFO) {
+		t.Error("global level should be warn")
+	}
+	if !Named("frame").Named("route").Enabled(DEBUG) {
+		t.Error("frame.route should inherit frame level")
+	}
+	if Named("frame.discover").Enabled(WARNING) {
+		t.Error("frame.discover level should be error")
+	}
+	if !Named("frame.discover").WithDebug().Enabled(DEBUG) {
+		t.Error("debug logger should log all levels")
+	}
+	if err := SetNamedLevel("frame", ""); err != nil || Named("frame").Enabled(INFO) {
+		t.Error("remove named level fail")
+	}
+	if err := SetLevels("verbose"); err == nil {
+		t.Error("unknown level should fail")
+	}
+}
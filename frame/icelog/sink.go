@@ -0,0 +1,306 @@
+package icelog
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 日志输出管道
+// 日志编码后放入有界队列，由单独的goroutine写入所有Sink，业务goroutine不会被慢速输出阻塞;
+// 队列满时按策略阻塞等待(默认，不丢日志)或丢弃并计数;
+// 程序退出前调用Close，保证队列中的日志全部写出
+
+// 队列满时的处理策略
+const (
+	PolicyBlock = iota // 阻塞等待
+	PolicyDrop         // 丢弃
+)
+
+var errPipeClosed = errors.New("icelog:pipeline closed")
+
+// Sink 日志输出目标
+type Sink interface {
+	Write(level int, b []byte) error
+	Flush() error
+	Close() error
+}
+
+// PipeStats 管道统计
+type PipeStats struct {
+	Queued  int    // 队列中待写出的日志数
+	Dropped uint64 // 队列满被丢弃的日志数
+	Blocked uint64 // 队列满阻塞等待的次数
+	Failed  uint64 // 写入Sink失败的次数
+}
+
+type leveledSink struct {
+	sink  Sink
+	level int
+}
+
+type pipeEntry struct {
+	level int
+	b     []byte
+	flush chan struct{} // 不为空表示Flush请求
+}
+
+// pipeline 异步日志管道
+type pipeline struct {
+	locker sync.RWMutex
+	queue  chan pipeEntry
+	policy int
+	sinks  []leveledSink
+	closed bool
+	quit   chan struct{} // 关闭后不再接收日志
+	done   chan struct{} // 队列中的日志全部写出
+
+	dropped uint64
+	blocked uint64
+	failed  uint64
+}
+
+func newPipeline(size, policy int) *pipeline {
+	if size <= 0 {
+		size = 4096
+	}
+	p := &pipeline{
+		queue:  make(chan pipeEntry, size),
+		policy: policy,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *pipeline) run() {
+	defer close(p.done)
+	for {
+		select {
+		case e := <-p.queue:
+			p.write(e)
+		case <-p.quit:
+			// 写出关闭前已入队的日志
+			for {
+				select {
+				case e := <-p.queue:
+					p.write(e)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *pipeline) write(e pipeEntry) {
+	p.locker.RLock()
+	sinks := p.sinks
+	p.locker.RUnlock()
+	if e.flush != nil {
+		for _, s := range sinks {
+			s.sink.Flush()
+		}
+		close(e.flush)
+		return
+	}
+	for _, s := range sinks {
+		if e.level < s.level {
+			continue
+		}
+		if err := s.sink.Write(e.level, e.b); err != nil {
+			atomic.AddUint64(&p.failed, 1)
+		}
+	}
+}
+
+func (p *pipeline) add(sink Sink, level int) {
+	p.locker.Lock()
+	sinks := make([]leveledSink, 0, len(p.sinks)+1)
+	sinks = append(sinks, p.sinks...)
+	p.sinks = append(sinks, leveledSink{sink: sink, level: level})
+	p.locker.Unlock()
+}
+
+// send 日志入队，阻塞等待时不持有锁，关闭管道时立即返回
+func (p *pipeline) send(e pipeEntry) error {
+	p.locker.RLock()
+	closed := p.closed
+	p.locker.RUnlock()
+	if closed {
+		atomic.AddUint64(&p.dropped, 1)
+		return errPipeClosed
+	}
+	select {
+	case p.queue <- e:
+		return nil
+	default:
+	}
+	if p.policy == PolicyDrop && e.flush == nil {
+		atomic.AddUint64(&p.dropped, 1)
+		return nil
+	}
+	atomic.AddUint64(&p.blocked, 1)
+	select {
+	case p.queue <- e:
+		return nil
+	case <-p.quit:
+		atomic.AddUint64(&p.dropped, 1)
+		return errPipeClosed
+	}
+}
+
+// flush 等待队列中已有的日志写出
+func (p *pipeline) flush() error {
+	ch := make(chan struct{})
+	if err := p.send(pipeEntry{flush: ch}); err != nil {
+		return err
+	}
+	select {
+	case <-ch:
+	case <-p.done:
+	}
+	return nil
+}
+
+// stop 关闭队列并等待队列中的日志写出，不关闭Sink
+func (p *pipeline) stop() bool {
+	p.locker.Lock()
+	if p.closed {
+		p.locker.Unlock()
+		return false
+	}
+	p.closed = true
+	close(p.quit)
+	p.locker.Unlock()
+	<-p.done
+	return true
+}
+
+func (p *pipeline) close() error {
+	if !p.stop() {
+		return nil
+	}
+	var err error
+	for _, s := range p.sinks {
+		s.sink.Flush()
+		if cerr := s.sink.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (p *pipeline) stats() PipeStats {
+	return PipeStats{
+		Queued:  len(p.queue),
+		Dropped: atomic.LoadUint64(&p.dropped),
+		Blocked: atomic.LoadUint64(&p.blocked),
+		Failed:  atomic.LoadUint64(&p.failed),
+	}
+}
+
+// WriterSink 输出到io.Writer
+type WriterSink struct {
+	w io.Writer
+}
+
+// NewWriterSink new writer sink
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewConsoleSink 输出到标准输出
+func NewConsoleSink() *WriterSink {
+	return &WriterSink{w: os.Stdout}
+}
+
+// Write write
+func (s *WriterSink) Write(level int, b []byte) error {
+	_, err := s.w.Write(b)
+	return err
+}
+
+// Flush flush
+func (s *WriterSink) Flush() error { return nil }
+
+// Close close
+func (s *WriterSink) Close() error { return nil }
+
+// FileSink 输出到轮转文件
+type FileSink struct {
+	fw *FileWriter
+}
+
+// NewFileSink new file sink
+func NewFileSink(path string, opts RotateOptions) (*FileSink, error) {
+	fw, err := NewRotateWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{fw: fw}, nil
+}
+
+// Write write
+func (s *FileSink) Write(level int, b []byte) error {
+	return s.fw.Write(string(b))
+}
+
+// Flush flush
+func (s *FileSink) Flush() error {
+	return s.fw.Flush()
+}
+
+// Close close
+func (s *FileSink) Close() error {
+	return s.fw.Close()
+}
+
+// NetSink 通过UDP或TCP发送到日志收集agent，连接断开后自动重连
+type NetSink struct {
+	network string
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+// NewNetSink network为udp或tcp
+func NewNetSink(network, addr string) *NetSink {
+	return &NetSink{network: network, addr: addr, timeout: time.Second}
+}
+
+// Write write，管道中只有一个goroutine写入，不需要加锁
+func (s *NetSink) Write(level int, b []byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	if _, err := s.conn.Write(b); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Flush flush
+func (s *NetSink) Flush() error { return nil }
+
+// Close close
+func (s *NetSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
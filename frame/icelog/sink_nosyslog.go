@@ -0,0 +1,23 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package icelog
+
+import "errors"
+
+// SyslogSink 当前平台不支持syslog
+type SyslogSink struct{}
+
+// NewSyslogSink 当前平台不支持syslog
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	return nil, errors.New("icelog:syslog not supported on this platform")
+}
+
+// Write write
+func (s *SyslogSink) Write(level int, b []byte) error { return nil }
+
+// Flush flush
+func (s *SyslogSink) Flush() error { return nil }
+
+// Close close
+func (s *SyslogSink) Close() error { return nil }
@@ -0,0 +1,47 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package icelog
+
+import (
+	"log/syslog"
+)
+
+// SyslogSink 输出到syslog，日志级别映射为syslog的级别
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink network,addr为空表示本机syslog
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_LOCAL0, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Write write
+func (s *SyslogSink) Write(level int, b []byte) error {
+	m := string(b)
+	switch level {
+	case DEBUG:
+		return s.w.Debug(m)
+	case INFO:
+		return s.w.Info(m)
+	case WARNING:
+		return s.w.Warning(m)
+	case ERROR:
+		return s.w.Err(m)
+	default:
+		return s.w.Crit(m)
+	}
+}
+
+// Flush flush
+func (s *SyslogSink) Flush() error { return nil }
+
+// Close close
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,151 @@
+package icelog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type slowSink struct {
+	sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (s *slowSink) Write(level int, b []byte) error {
+	<-s.release
+	s.Lock()
+	s.buf.Write(b)
+	s.Unlock()
+	return nil
+}
+func (s *slowSink) Flush() error { return nil }
+func (s *slowSink) Close() error { return nil }
+
+func TestPipeline(t *testing.T) {
+	var all, errs bytes.Buffer
+	p := newPipeline(16, PolicyBlock)
+	p.add(NewWriterSink(&all), DEBUG)
+	p.add(NewWriterSink(&errs), ERROR)
+	for i := 0; i < 100; i++ {
+		p.send(pipeEntry{level: i % 5, b: []byte("x\n")})
+	}
+	if err := p.flush(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if n := strings.Count(all.String(), "\n"); n != 100 {
+		t.Errorf("block policy lost logs,got %d", n)
+	}
+	if n := strings.Count(errs.String(), "\n"); n != 40 {
+		t.Errorf("level filter fail,got %d", n)
+	}
+	p.close()
+	if err := p.send(pipeEntry{level: INFO, b: []byte("x\n")}); err == nil {
+		t.Error("send after close should fail")
+	}
+}
+
+func TestPipelineDrop(t *testing.T) {
+	sink := &slowSink{release: make(chan struct{})}
+	p := newPipeline(4, PolicyDrop)
+	p.add(sink, DEBUG)
+	for i := 0; i < 10; i++ {
+		p.send(pipeEntry{level: INFO, b: []byte("x\n")})
+	}
+	close(sink.release)
+	p.close()
+	st := p.stats()
+	written := strings.Count(sink.buf.String(), "\n")
+	if st.Dropped == 0 || uint64(written)+st.Dropped != 10 {
+		t.Errorf("written %d,stats %+v", written, st)
+	}
+}
+
+func TestPipelineCloseWhenFull(t *testing.T) {
+	sink := &slowSink{release: make(chan struct{})}
+	p := newPipeline(2, PolicyBlock)
+	p.add(sink, DEBUG)
+
+	// 队列已满，发送方阻塞等待
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.send(pipeEntry{level: INFO, b: []byte("x\n")})
+		}()
+	}
+	for p.stats().Blocked == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.close()
+		p.add(NewWriterSink(&bytes.Buffer{}), DEBUG)
+		wg.Wait()
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(sink.release)
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("close deadlocked with blocked senders")
+	}
+	if err := p.flush(); err != errPipeClosed {
+		t.Errorf("flush after close got %v", err)
+	}
+}
+
+func TestSetPipelineConcurrent(t *testing.T) {
+	old := defaultLogger
+	l := NewLogger()
+	defer func() { defaultLogger = old }()
+
+	var all lockedBuffer
+	AddSink(NewWriterSink(&all), DEBUG)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				l.Info("x")
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		SetPipeline(8, PolicyBlock)
+		AddSink(NewWriterSink(ioutil.Discard), ERROR)
+		Stats()
+	}
+	wg.Wait()
+	if err := Flush(); err != nil {
+		t.Fatal(err.Error())
+	}
+	if n := strings.Count(all.String(), "\n"); n != 800 {
+		t.Errorf("switch pipeline lost log,%d", n)
+	}
+	Close()
+}
+
+type lockedBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
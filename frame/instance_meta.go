@@ -0,0 +1,168 @@
+package frame
+
+import (
+	"encoding/json"
+	"runtime"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	"github.com/kwins/iceberg/frame/util"
+)
+
+// defaultWeight 实例的默认权重
+const defaultWeight = 100
+
+// InstanceMeta 实例元数据
+// 注册在etcd中 /services/v1/<svc>/provider/meta/<addr>，值为JSON
+type InstanceMeta struct {
+	Name      string            `json:"name"`       // 服务名称
+	Addr      string            `json:"addr"`       // 实例监听地址
+	Version   string            `json:"version"`    // 服务版本
+	Zone      string            `json:"zone"`       // 可用区
+	Region    string            `json:"region"`     // 地域
+	Weight    int               `json:"weight"`     // 权重，按比例分配请求
+	Tags      []string          `json:"tags"`       // 实例标签
+	Labels    map[string]string `json:"labels"`     // 自定义标签
+	Build     string            `json:"build"`      // 构建信息
+	GoVersion string            `json:"go_version"` // go版本
+	Hostname  string            `json:"hostname"`   // 主机名称
+	StartTime int64             `json:"start_time"` // 启动时间，unix秒
+}
+
+// HasTag 实例是否带有该标签
+func (meta *InstanceMeta) HasTag(tag string) bool {
+	for _, t := range meta.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MetaSelector 按实例元数据选择实例，所有条件都满足才算匹配
+// 空条件表示不限
+type MetaSelector struct {
+	Zone    string
+	Region  string
+	Version string
+	Tags    []string
+	Labels  map[string]string
+}
+
+// Match 实例是否满足选择条件
+func (sel *MetaSelector) Match(meta *InstanceMeta) bool {
+	if sel.Zone != "" && sel.Zone != meta.Zone {
+		return false
+	}
+	if sel.Region != "" && sel.Region != meta.Region {
+		return false
+	}
+	if sel.Version != "" && sel.Version != meta.Version {
+		return false
+	}
+	for _, tag := range sel.Tags {
+		if !meta.HasTag(tag) {
+			return false
+		}
+	}
+	for k, v := range sel.Labels {
+		if meta.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (sel *MetaSelector) empty() bool {
+	return sel == nil || (sel.Zone == "" && sel.Region == "" && sel.Version == "" &&
+		len(sel.Tags) == 0 && len(sel.Labels) == 0)
+}
+
+func newInstanceMeta(name, version, addr string, cfg *config.MetaCfg) *InstanceMeta {
+	meta := &InstanceMeta{
+		Name:      name,
+		Addr:      addr,
+		Version:   version,
+		Zone:      cfg.Zone,
+		Region:    cfg.Region,
+		Weight:    cfg.Weight,
+		Tags:      cfg.Tags,
+		Labels:    cfg.Labels,
+		Build:     cfg.Build,
+		GoVersion: runtime.Version(),
+		Hostname:  util.GetHostname(),
+		StartTime: time.Now().Unix(),
+	}
+	if meta.Weight <= 0 {
+		meta.Weight = defaultWeight
+	}
+	return meta
+}
+
+// setMeta 更新实例的元数据
+func (discover *Discover) setMeta(svrAddr, value string) {
+	var meta InstanceMeta
+	if err := json.Unmarshal([]byte(value), &meta); err != nil {
+		discoverLog.Errorf("iceberg:bad instance meta of %s,detail=%s", svrAddr, err.Error())
+		return
+	}
+	meta.Addr = svrAddr
+	discover.metaLocker.Lock()
+	discover.metas[svrAddr] = &meta
+	discover.metaLocker.Unlock()
+}
+
+func (discover *Discover) rmMeta(svrAddr string) {
+	discover.metaLocker.Lock()
+	delete(discover.metas, svrAddr)
+	discover.metaLocker.Unlock()
+}
+
+// Meta 获取实例的元数据，实例没有注册元数据时返回false
+func (discover *Discover) Meta(svrAddr string) (InstanceMeta, bool) {
+	discover.metaLocker.RLock()
+	defer discover.metaLocker.RUnlock()
+	if meta, ok := discover.metas[svrAddr]; ok {
+		return *meta, true
+	}
+	return InstanceMeta{Addr: svrAddr}, false
+}
+
+// SelfMeta 当前实例的元数据
+func (discover *Discover) SelfMeta() InstanceMeta {
+	if discover.selfMeta == nil {
+		return InstanceMeta{}
+	}
+	return *discover.selfMeta
+}
+
+// Instances 获取服务的所有实例及其元数据
+func (discover *Discover) Instances(URI string) []InstanceMeta {
+	discover.topoLocker.RLock()
+	node, ok := discover.topology[URI]
+	discover.topoLocker.RUnlock()
+	if !ok {
+		return nil
+	}
+	node.RLock()
+	addrs := node.AllNode()
+	node.RUnlock()
+
+	var metas = make([]InstanceMeta, 0, len(addrs))
+	for _, addr := range addrs {
+		meta, _ := discover.Meta(addr)
+		metas = append(metas, meta)
+	}
+	return metas
+}
+
+// selector 生成按元数据过滤实例的函数，选择条件为空时返回nil
+func (discover *Discover) selector(sel *MetaSelector) func(string) bool {
+	if sel.empty() {
+		return nil
+	}
+	return func(svrAddr string) bool {
+		meta, _ := discover.Meta(svrAddr)
+		return sel.Match(&meta)
+	}
+}
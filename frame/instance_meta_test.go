@@ -0,0 +1,29 @@
+package frame
+
+import "testing"
+
+func TestMetaSelector(t *testing.T) {
+	meta := InstanceMeta{
+		Zone:    "sh-a",
+		Version: "v1",
+		Tags:    []string{"canary", "blue"},
+		Labels:  map[string]string{"team": "pay"},
+	}
+	var cases = []struct {
+		sel  MetaSelector
+		want bool
+	}{
+		{MetaSelector{}, true},
+		{MetaSelector{Zone: "sh-a"}, true},
+		{MetaSelector{Zone: "sh-b"}, false},
+		{MetaSelector{Tags: []string{"canary"}}, true},
+		{MetaSelector{Tags: []string{"canary", "green"}}, false},
+		{MetaSelector{Version: "v1", Labels: map[string]string{"team": "pay"}}, true},
+		{MetaSelector{Labels: map[string]string{"team": "order"}}, false},
+	}
+	for i, c := range cases {
+		if got := c.sel.Match(&meta); got != c.want {
+			t.Errorf("case %d got %v want %v", i, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package frame
+
+import (
+	goctx "context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/kwins/iceberg/frame/icelog"
+)
+
+// 内置拦截器
+
+// Recovery 捕获内层拦截器和处理方法中的panic，返回PanicError
+// 框架在调用链最外层总会恢复panic，使用Recovery可以让外层的拦截器(如Logging,Metrics)看到错误
+func Recovery() Interceptor {
+	return func(c Context, next Next) (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = RecoverPanic(rec, c.Request())
+			}
+		}()
+		return next(c)
+	}
+}
+
+// Logging 输出每个请求的处理结果和耗时
+func Logging() Interceptor {
+	return func(c Context, next Next) error {
+		start := time.Now()
+		err := next(c)
+		if err != nil {
+			c.Logger().Errorw("handle fail", log.Duration("cost", time.Since(start)), log.Err(err))
+		} else {
+			c.Logger().Infow("handle done", log.Duration("cost", time.Since(start)))
+		}
+		return err
+	}
+}
+
+// Metrics 统计每个方法的请求数，失败数和耗时，通过MethodStats获取
+func Metrics() Interceptor {
+	return func(c Context, next Next) error {
+		start := time.Now()
+		err := next(c)
+		Instance().methodStat(c.Request().GetServeMethod()).add(time.Since(start), err)
+		return err
+	}
+}
+
+// Timeout 设置处理方法的超时时间
+// 超时是协作式的，Ctx()在超时后取消，使用该Context发起的下游请求立即失败;处理方法超时后返回ErrTimeout
+func Timeout(d time.Duration) Interceptor {
+	return func(c Context, next Next) error {
+		ic, ok := c.(*icecontext)
+		if !ok {
+			return next(c)
+		}
+		cancel := ic.withTimeout(d)
+		defer cancel()
+		err := next(c)
+		// 超时后处理方法返回的通常是context或下游请求的错误，统一为ErrTimeout
+		if ic.ctx.Err() == goctx.DeadlineExceeded {
+			return ErrTimeout
+		}
+		return err
+	}
+}
+
+// MethodStat 方法调用统计
+type MethodStat struct {
+	Count   int64 `json:"count"`
+	Fail    int64 `json:"fail"`
+	Panic   int64 `json:"panic"`
+	Cost    int64 `json:"cost_ms"`     // 总耗时
+	MaxCost int64 `json:"max_cost_ms"` // 最大耗时
+}
+
+func (st *MethodStat) add(cost time.Duration, err error) {
+	atomic.AddInt64(&st.Count, 1)
+	if err != nil {
+		atomic.AddInt64(&st.Fail, 1)
+	}
+	ms := int64(cost / time.Millisecond)
+	atomic.AddInt64(&st.Cost, ms)
+	for {
+		max := atomic.LoadInt64(&st.MaxCost)
+		if ms <= max || atomic.CompareAndSwapInt64(&st.MaxCost, max, ms) {
+			break
+		}
+	}
+}
+
+func (st *MethodStat) addPanic() {
+	atomic.AddInt64(&st.Panic, 1)
+}
+
+// methodStats 方法调用统计，key为方法名称
+type methodStats struct {
+	locker sync.RWMutex
+	m      map[string]*MethodStat
+}
+
+func (discover *Discover) methodStat(method string) *MethodStat {
+	discover.stats.locker.RLock()
+	st, ok := discover.stats.m[method]
+	discover.stats.locker.RUnlock()
+	if ok {
+		return st
+	}
+	discover.stats.locker.Lock()
+	defer discover.stats.locker.Unlock()
+	if st, ok = discover.stats.m[method]; !ok {
+		if discover.stats.m == nil {
+			discover.stats.m = make(map[string]*MethodStat)
+		}
+		st = new(MethodStat)
+		discover.stats.m[method] = st
+	}
+	return st
+}
+
+// MethodStats 获取方法调用统计
+func MethodStats() map[string]MethodStat {
+	discover := Instance()
+	discover.stats.locker.RLock()
+	defer discover.stats.locker.RUnlock()
+	var stats = make(map[string]MethodStat, len(discover.stats.m))
+	for k, st := range discover.stats.m {
+		stats[k] = MethodStat{
+			Count:   atomic.LoadInt64(&st.Count),
+			Fail:    atomic.LoadInt64(&st.Fail),
+			Panic:   atomic.LoadInt64(&st.Panic),
+			Cost:    atomic.LoadInt64(&st.Cost),
+			MaxCost: atomic.LoadInt64(&st.MaxCost),
+		}
+	}
+	return stats
+}
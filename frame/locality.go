@@ -0,0 +1,90 @@
+package frame
+
+import (
+	"github.com/kwins/iceberg/frame/config"
+)
+
+// 就近路由
+// 调用方优先选择与自己同可用区(zone)的实例，同可用区健康实例不足时
+// 依次溢出到同地域(region)和所有实例，避免跨可用区调用带来的延迟和费用
+
+// setLocality 设置就近路由配置
+func (discover *Discover) setLocality(cfg config.LocalityCfg) {
+	if cfg.MinHealthy <= 0 {
+		cfg.MinHealthy = 1
+	}
+	discover.locality = cfg
+}
+
+// healthy 实例当前是否可用
+// 被摘除的实例不可用，没有建立过连接的实例认为是可用的
+func (discover *Discover) healthy(svrAddr string) bool {
+	if discover.outlier.Ejected(svrAddr) {
+		return false
+	}
+	discover.connLocker.RLock()
+	ca, found := discover.connholder[svrAddr]
+	discover.connLocker.RUnlock()
+	if !found || ca == nil {
+		return true
+	}
+	return ca.Status() == CA_OK
+}
+
+// localize 按就近原则缩小候选实例范围
+// 返回的过滤条件包含accept
+func (discover *Discover) localize(node *ConsistentHash, accept func(string) bool) func(string) bool {
+	self := discover.selfMeta
+	if !discover.locality.Enable || self == nil || (self.Zone == "" && self.Region == "") {
+		return accept
+	}
+
+	node.RLock()
+	addrs := node.AllNode()
+	node.RUnlock()
+
+	var candidates []InstanceMeta
+	for _, addr := range addrs {
+		if accept != nil && !accept(addr) {
+			continue
+		}
+		meta, _ := discover.Meta(addr)
+		candidates = append(candidates, meta)
+	}
+
+	tiers := []*MetaSelector{}
+	if self.Zone != "" {
+		tiers = append(tiers, &MetaSelector{Zone: self.Zone})
+	}
+	if self.Region != "" {
+		tiers = append(tiers, &MetaSelector{Region: self.Region})
+	}
+	for _, sel := range tiers {
+		var total, healthy int
+		for i := range candidates {
+			if !sel.Match(&candidates[i]) {
+				continue
+			}
+			total++
+			if discover.healthy(candidates[i].Addr) {
+				healthy++
+			}
+		}
+		if discover.enough(total, healthy) {
+			sel := sel
+			return both(accept, func(addr string) bool {
+				meta, _ := discover.Meta(addr)
+				return sel.Match(&meta) && discover.healthy(addr)
+			})
+		}
+	}
+	return accept
+}
+
+// enough 本地健康实例是否足够承接流量
+func (discover *Discover) enough(total, healthy int) bool {
+	if total == 0 || healthy < discover.locality.MinHealthy {
+		return false
+	}
+	return healthy*100 >= total*discover.locality.MinPercent
+}
@@ -0,0 +1,70 @@
+package frame
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+)
+
+func TestLocalize(t *testing.T) {
+	// a1,a2 同可用区; b1 同地域; c1 其他地域
+	metas := map[string]*InstanceMeta{
+		"a1": {Addr: "a1", Zone: "z1", Region: "r1"},
+		"a2": {Addr: "a2", Zone: "z1", Region: "r1"},
+		"b1": {Addr: "b1", Zone: "z2", Region: "r1"},
+		"c1": {Addr: "c1", Zone: "z3", Region: "r2"},
+	}
+	node := NewConsistentHash()
+	for addr := range metas {
+		node.AddNode(addr)
+	}
+	notA := func(addr string) bool { return !strings.HasPrefix(addr, "a") }
+
+	var cases = []struct {
+		name    string
+		cfg     config.LocalityCfg
+		ejected []string
+		broken  []string
+		accept  func(string) bool
+		want    string
+	}{
+		{"disabled", config.LocalityCfg{}, nil, nil, nil, "a1,a2,b1,c1"},
+		{"same zone", config.LocalityCfg{Enable: true}, nil, nil, nil, "a1,a2"},
+		{"skip ejected", config.LocalityCfg{Enable: true}, []string{"a1"}, nil, nil, "a2"},
+		{"zone down to region", config.LocalityCfg{Enable: true}, []string{"a1"}, []string{"a2"}, nil, "b1"},
+		{"min healthy", config.LocalityCfg{Enable: true, MinHealthy: 2}, []string{"a1"}, nil, nil, "a2,b1"},
+		{"min percent", config.LocalityCfg{Enable: true, MinPercent: 60}, nil, []string{"a2"}, nil, "a1,b1"},
+		{"region down to all", config.LocalityCfg{Enable: true, MinPercent: 100}, []string{"b1"}, []string{"a1"}, nil, "a1,a2,b1,c1"},
+		{"with accept", config.LocalityCfg{Enable: true}, nil, nil, notA, "b1"},
+	}
+	for _, c := range cases {
+		discover := &Discover{
+			metas:      metas,
+			connholder: make(map[string]*ConnActor),
+			selfMeta:   &InstanceMeta{Zone: "z1", Region: "r1"},
+			outlier:    NewOutlierDetector(config.OutlierCfg{Enable: true}),
+		}
+		discover.setLocality(c.cfg)
+		for _, addr := range c.ejected {
+			discover.outlier.stats[addr] = &instanceStat{ejectedUntil: time.Now().Add(time.Minute)}
+		}
+		for _, addr := range c.broken {
+			discover.connholder[addr] = &ConnActor{status: CA_BROKEN}
+		}
+
+		filter := discover.localize(node, c.accept)
+		var got []string
+		for addr := range metas {
+			if filter == nil || filter(addr) {
+				got = append(got, addr)
+			}
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != c.want {
+			t.Errorf("%s: want %s,got %v", c.name, c.want, got)
+		}
+	}
+}
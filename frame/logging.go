@@ -0,0 +1,70 @@
+package frame
+
+import (
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	log "github.com/kwins/iceberg/frame/icelog"
+)
+
+// initLog 按配置初始化日志
+func initLog(srvName string, cfg *config.LogCfg) {
+	log.SetService(srvName)
+	if err := log.SetFormat(cfg.Format); err != nil {
+		log.Error(err.Error())
+	}
+	if cfg.Level != "" {
+		log.SetLevel(cfg.Level)
+	}
+	rotate := log.RotateOptions{
+		Pattern:  cfg.Pattern,
+		MaxSize:  int64(cfg.MaxSize) << 20,
+		MaxFiles: cfg.MaxFiles,
+		MaxAge:   time.Duration(cfg.MaxAge) * 24 * time.Hour,
+		Compress: cfg.Compress,
+	}
+
+	if len(cfg.Sinks) == 0 {
+		if cfg.File != "" {
+			if err := log.SetRotateLog(cfg.File, "", rotate); err != nil {
+				log.Errorf("iceberg:open log file %s fail,%s", cfg.File, err.Error())
+			}
+		}
+		return
+	}
+
+	policy := log.PolicyBlock
+	if cfg.DropWhenFull {
+		policy = log.PolicyDrop
+	}
+	log.SetPipeline(cfg.QueueSize, policy)
+	for _, sc := range cfg.Sinks {
+		sink, err := newSink(srvName, sc, rotate)
+		if err != nil {
+			log.Errorf("iceberg:create log sink %s %s fail,%s", sc.Type, sc.Addr, err.Error())
+			continue
+		}
+		level, _ := log.ParseLevel(sc.Level)
+		log.AddSink(sink, level)
+	}
+}
+
+func newSink(srvName string, sc config.SinkCfg, rotate log.RotateOptions) (log.Sink, error) {
+	switch sc.Type {
+	case "file":
+		return log.NewFileSink(sc.Addr, rotate)
+	case "syslog":
+		tag := sc.Tag
+		if tag == "" {
+			tag = srvName
+		}
+		var network string
+		if sc.Addr != "" {
+			network = "udp"
+		}
+		return log.NewSyslogSink(network, sc.Addr, tag)
+	case "udp", "tcp":
+		return log.NewNetSink(sc.Type, sc.Addr), nil
+	}
+	return log.NewConsoleSink(), nil
+}
@@ -0,0 +1,68 @@
+package frame
+
+import (
+	"context"
+	"strings"
+
+	log "github.com/kwins/iceberg/frame/icelog"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 运行时修改日志级别
+// etcd中 /services/v1/<svc>/provider/loglevel 的值为单个级别如 info，
+// 或者JSON如 {"":"info","frame.discover":"debug"}，修改后本服务所有实例实时生效，
+// 删除该key恢复启动时的级别;
+// 框架各组件使用下面的命名Logger，可以单独设置级别，frame 对所有组件生效
+
+var (
+	discoverLog = log.Named("frame.discover") // 服务注册发现和etcd同步
+	routeLog    = log.Named("frame.route")    // 路由，一致性哈希，就近路由
+	connLog     = log.Named("frame.conn")     // 连接和请求收发
+	outlierLog  = log.Named("frame.outlier")  // 异常实例检测
+	traceLog    = log.Named("frame.trace")    // 链路追踪
+)
+
+// setLogLevel 同步etcd中本服务的日志级别，value为空表示恢复启动时的级别
+func (discover *Discover) setLogLevel(URI, value string) {
+	if !discover.isSelf(URI) {
+		return
+	}
+	if value == "" {
+		value = discover.startLevel
+	}
+	if err := log.SetLevels(value); err != nil {
+		discoverLog.Errorf("iceberg:%s bad log level %s,detail=%s", URI, value, err.Error())
+		return
+	}
+	discoverLog.Infof("iceberg:%s log level changed to %s", URI, value)
+}
+
+// PutLogLevel 修改服务所有实例的日志级别，value为空表示恢复启动时的级别
+func (discover *Discover) PutLogLevel(URI, value string) error {
+	key := URI + "/provider/loglevel"
+	if value == "" {
+		_, err := discover.kapi.Delete(context.TODO(), key)
+		return err
+	}
+	_, err := discover.kapi.Put(context.TODO(), key, value)
+	return err
+}
+
+// isSelf URI是否为本服务
+func (discover *Discover) isSelf(URI string) bool {
+	for _, uri := range discover.selfURI {
+		if uri == URI {
+			return true
+		}
+	}
+	return false
+}
+
+// debugRequest 请求是否带有调试标记
+func debugRequest(req *protocol.Proto) bool {
+	switch strings.ToLower(headerValue(req.GetHeader(), protocol.HeaderXIcebergDebug)) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
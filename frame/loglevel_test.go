@@ -0,0 +1,23 @@
+package frame
+
+import (
+	"testing"
+
+	log "github.com/kwins/iceberg/frame/icelog"
+)
+
+func TestComponentLogLevel(t *testing.T) {
+	defer log.SetLevels("debug")
+	if err := log.SetLevels(`{"":"info","frame":"warn","frame.discover":"debug"}`); err != nil {
+		t.Fatal(err)
+	}
+	if !discoverLog.Enabled(log.DEBUG) {
+		t.Error("frame.discover should be debug")
+	}
+	if routeLog.Enabled(log.INFO) || connLog.Enabled(log.INFO) || !outlierLog.Enabled(log.WARNING) {
+		t.Error("frame components should inherit frame level")
+	}
+	if !log.Default().Enabled(log.INFO) {
+		t.Error("global level should be info")
+	}
+}
@@ -2,11 +2,13 @@ package frame
 
 // Medesc 方法描述
 // 是否能无认证访问
+// 接受的HTTP方法
 // 流量统计
 // 失败统计
 type Medesc struct {
-	MdName  string `json:"md_name"`
-	Allowed bool   `json:"allowed"`
-	FailCnt int64  `json:"fail_cnt"`
-	Cnt     int64  `json:"cnt"`
+	MdName  string   `json:"md_name"`
+	Allowed bool     `json:"allowed"`
+	Methods []string `json:"methods,omitempty"`
+	FailCnt int64    `json:"fail_cnt"`
+	Cnt     int64    `json:"cnt"`
 }
@@ -0,0 +1,36 @@
+package frame
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMethodTable(t *testing.T) {
+	discover := &Discover{mdtables: make(map[string]*Medesc)}
+	// methods可能先于allowed同步到
+	discover.setTopo("/services/v1/order/state/provider/methods", "get, head")
+	discover.setTopo("/services/v1/order/state/provider/allowed/true", "state")
+	discover.setTopo("/services/v1/order/create/provider/allowed/false", "create")
+
+	if methods, ok := discover.Methods("/services/v1/order/State"); !ok || strings.Join(methods, ",") != "GET,HEAD" {
+		t.Errorf("methods fail,%v", methods)
+	}
+	if !discover.Allowed("/services/v1/order/state") {
+		t.Error("allowed lost after methods synced")
+	}
+	if methods, ok := discover.Methods("/services/v1/order/create"); !ok || methods != nil {
+		t.Errorf("undeclared methods should be nil,got %v", methods)
+	}
+	if methods, ok := discover.Methods("/services/v1"); ok || methods != nil {
+		t.Errorf("short path should be nil,got %v", methods)
+	}
+	if _, ok := discover.Methods("/services/v1/order/unknown"); ok {
+		t.Error("unknown method should not be found")
+	}
+
+	// 服务不再声明HTTP方法
+	discover.rmTopo("/services/v1/order/state/provider/methods", "")
+	if methods, ok := discover.Methods("/services/v1/order/state"); !ok || methods != nil {
+		t.Errorf("removed methods should be nil,got %v", methods)
+	}
+}
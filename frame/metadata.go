@@ -0,0 +1,115 @@
+package frame
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 请求元数据
+// 允许透传的Header(如租户，用户，语言，A/B标记)在baseCfg的metadataCfg.forward中配置，
+// 服务收到的请求中带有这些Header时，使用该Context发起的下游请求自动带上;
+// OutgoingMetadata 设置只发给下游的元数据，覆盖透传的值，frame.Header选项的优先级最高;
+// gateway收到不可信客户端的请求时删除这些Header和baggage，防止外部伪造
+
+// Metadata 请求元数据，key为规范化的Header名称
+type Metadata map[string]string
+
+// Get 获取元数据
+func (md Metadata) Get(key string) string {
+	return md[http.CanonicalHeaderKey(key)]
+}
+
+// Set 设置元数据
+func (md Metadata) Set(key, val string) {
+	md[http.CanonicalHeaderKey(key)] = val
+}
+
+// Del 删除元数据
+func (md Metadata) Del(key string) {
+	delete(md, http.CanonicalHeaderKey(key))
+}
+
+// SetForwardMetadata 设置允许透传的Header
+func SetForwardMetadata(keys ...string) {
+	forward := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k = strings.TrimSpace(k); k != "" {
+			forward = append(forward, http.CanonicalHeaderKey(k))
+		}
+	}
+	discover := Instance()
+	discover.forwardLocker.Lock()
+	discover.forward = forward
+	discover.forwardLocker.Unlock()
+}
+
+// ForwardMetadata 允许透传的Header
+func ForwardMetadata() []string {
+	discover := Instance()
+	discover.forwardLocker.RLock()
+	defer discover.forwardLocker.RUnlock()
+	return discover.forward
+}
+
+// IncomingMetadata 请求中允许透传的元数据
+func IncomingMetadata(c Context) Metadata {
+	md := make(Metadata)
+	header := c.Request().GetHeader()
+	if len(header) == 0 {
+		return md
+	}
+	for _, k := range ForwardMetadata() {
+		if v := headerValue(header, k); v != "" {
+			md[k] = v
+		}
+	}
+	return md
+}
+
+// OutgoingMetadata 发往下游的元数据，修改后对该Context之后发起的请求生效，不能并发修改
+func OutgoingMetadata(c Context) Metadata {
+	vals, ok := c.Ctx().Value(valuesKey).(*values)
+	if !ok {
+		return make(Metadata)
+	}
+	vals.locker.Lock()
+	defer vals.locker.Unlock()
+	if vals.md == nil {
+		vals.md = make(Metadata)
+	}
+	return vals.md
+}
+
+// injectMetadata 将透传的元数据和OutgoingMetadata写入下游请求的Header
+func injectMetadata(c Context, task *protocol.Proto) {
+	for k, v := range IncomingMetadata(c) {
+		task.SetHeader(k, v)
+	}
+	if vals, ok := c.Ctx().Value(valuesKey).(*values); ok {
+		vals.locker.RLock()
+		for k, v := range vals.md {
+			task.SetHeader(k, v)
+		}
+		vals.locker.RUnlock()
+	}
+}
+
+// StripMetadata 删除请求中允许透传的Header和baggage，gateway用于不可信的客户端
+func StripMetadata(task *protocol.Proto) {
+	forward := ForwardMetadata()
+	for k := range task.GetHeader() {
+		ck := http.CanonicalHeaderKey(k)
+		if strings.HasPrefix(ck, protocol.HeaderXIcebergBaggagePrefix) {
+			task.DelHeader(k)
+			continue
+		}
+		for _, f := range forward {
+			if ck == f {
+				task.DelHeader(k)
+				break
+			}
+		}
+	}
+}
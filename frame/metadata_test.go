@@ -0,0 +1,41 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+func TestMetadataForward(t *testing.T) {
+	SetForwardMetadata("x-tenant-id", "X-User-Id")
+	defer SetForwardMetadata()
+
+	c := NewContext()
+	c.Reset(&protocol.Proto{Bizid: "b1", Header: map[string]string{
+		"X-Tenant-Id": "t1",
+		"X-User-Id":   "u1",
+		"X-Other":     "o",
+	}}, &protocol.Proto{})
+
+	if md := IncomingMetadata(c); len(md) != 2 || md.Get("x-tenant-id") != "t1" {
+		t.Errorf("incoming metadata got %v", md)
+	}
+	OutgoingMetadata(c).Set("x-user-id", "u2")
+	OutgoingMetadata(c).Set("Accept-Language", "zh")
+
+	task, err := ReadyTask(c, "echo", "echo", "v1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Header["X-Tenant-Id"] != "t1" || task.Header["X-User-Id"] != "u2" ||
+		task.Header["Accept-Language"] != "zh" || task.Header["X-Other"] != "" {
+		t.Errorf("outgoing header got %v", task.Header)
+	}
+
+	task.Header[protocol.HeaderXIcebergBaggagePrefix+"k"] = "v"
+	task.Header["x-tenant-id"] = "t2"
+	StripMetadata(task)
+	if len(task.Header) != 1 || task.Header["Accept-Language"] != "zh" {
+		t.Errorf("strip metadata got %v", task.Header)
+	}
+}
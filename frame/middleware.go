@@ -1,4 +1,139 @@
 package frame
 
+import (
+	"net/http"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 拦截器
+// 拦截器按洋葱模型执行，先注册的在外层，可以在调用next前后处理请求，不调用next时请求不会到达处理方法;
+// 服务端执行顺序: Use注册的全局拦截器 -> Prepare/After中间件 -> ServiceDesc和MethodDesc中的拦截器 -> UseMethod注册的拦截器 -> 处理方法;
+// 客户端拦截器包裹DeliverTo，同样按注册顺序由外到内执行
+
 // Middleware middle ware
+// Deprecated: 使用Interceptor
 type Middleware func(Context) error
+
+// Next 调用下一个拦截器或处理方法
+type Next func(Context) error
+
+// Interceptor 服务端拦截器
+type Interceptor func(c Context, next Next) error
+
+// Invoker 发送请求并等待响应
+type Invoker func(task *protocol.Proto, opts ...CallOption) (*protocol.Proto, error)
+
+// ClientInterceptor 客户端拦截器
+type ClientInterceptor func(task *protocol.Proto, invoker Invoker, opts ...CallOption) (*protocol.Proto, error)
+
+// StatusError 带响应状态码的错误，拦截器和处理方法返回该错误时使用其状态码响应
+type StatusError interface {
+	error
+	Status() int
+}
+
+// Use 添加全局拦截器
+func Use(interceptors ...Interceptor) {
+	discover := Instance()
+	discover.icLocker.Lock()
+	discover.interceptors = append(discover.interceptors, interceptors...)
+	discover.icLocker.Unlock()
+}
+
+// UseMethod 添加方法的拦截器，method为小写的方法名称
+func UseMethod(method string, interceptors ...Interceptor) {
+	discover := Instance()
+	discover.icLocker.Lock()
+	discover.methodInterceptors[method] = append(discover.methodInterceptors[method], interceptors...)
+	discover.icLocker.Unlock()
+}
+
+// UseClient 添加客户端拦截器
+func UseClient(interceptors ...ClientInterceptor) {
+	discover := Instance()
+	discover.icLocker.Lock()
+	discover.clientInterceptors = append(discover.clientInterceptors, interceptors...)
+	discover.icLocker.Unlock()
+}
+
+// chainInterceptors 将拦截器和处理方法组合成调用链
+func chainInterceptors(interceptors []Interceptor, h Next) Next {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], h
+		h = func(c Context) error {
+			return ic(c, next)
+		}
+	}
+	return h
+}
+
+// chainClientInterceptors 将客户端拦截器和发送方法组合成调用链
+func chainClientInterceptors(interceptors []ClientInterceptor, invoker Invoker) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], invoker
+		invoker = func(task *protocol.Proto, opts ...CallOption) (*protocol.Proto, error) {
+			return ic(task, next, opts...)
+		}
+	}
+	return invoker
+}
+
+// middlewareInterceptor 兼容Prepare/After中间件，After只在处理成功时执行
+func middlewareInterceptor(prepare, after []Middleware) Interceptor {
+	return func(c Context, next Next) error {
+		for i := range prepare {
+			if err := prepare[i](c); err != nil {
+				return err
+			}
+		}
+		if err := next(c); err != nil {
+			return err
+		}
+		for i := range after {
+			if err := after[i](c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// handler 方法的调用链
+func (discover *Discover) handler(md *MethodDesc) Next {
+	h := func(c Context) error {
+		return md.Handler(discover.service, c)
+	}
+	discover.icLocker.RLock()
+	mics := discover.methodInterceptors[md.MethodName]
+	ics := make([]Interceptor, 0, len(discover.interceptors)+len(discover.serviceInterceptors)+
+		len(md.Interceptors)+len(mics)+1)
+	ics = append(ics, discover.interceptors...)
+	if len(discover.prepare) > 0 || len(discover.after) > 0 {
+		ics = append(ics, middlewareInterceptor(discover.prepare, discover.after))
+	}
+	ics = append(ics, discover.serviceInterceptors...)
+	ics = append(ics, md.Interceptors...)
+	ics = append(ics, mics...)
+	discover.icLocker.RUnlock()
+	return chainInterceptors(ics, h)
+}
+
+// fillError 填充错误响应，清除处理方法已设置的状态码和响应Header(如Location，Set-Cookie)
+func fillError(resp *protocol.Proto, code int, err error) {
+	resp.Status = 0
+	resp.Header = nil
+	resp.HeaderValues = nil
+	resp.FillErrInfo(code, err)
+}
+
+// errStatus 错误对应的响应状态码
+func errStatus(err error) int {
+	if se, ok := err.(StatusError); ok {
+		return se.Status()
+	}
+	if err == ErrTimeout {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,100 @@
+package frame
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+func TestInterceptorChain(t *testing.T) {
+	var trace []string
+	mark := func(name string) Interceptor {
+		return func(c Context, next Next) error {
+			trace = append(trace, name+">")
+			err := next(c)
+			trace = append(trace, "<"+name)
+			return err
+		}
+	}
+	discover := &Discover{methodInterceptors: make(map[string][]Interceptor)}
+	discover.interceptors = []Interceptor{mark("global"), Metrics(), Recovery()}
+	discover.serviceInterceptors = []Interceptor{mark("service")}
+	discover.methodInterceptors["echo"] = []Interceptor{mark("method")}
+	discover.after = []Middleware{func(c Context) error {
+		trace = append(trace, "after")
+		return nil
+	}}
+	md := &MethodDesc{MethodName: "echo", Handler: func(srv interface{}, c Context) error {
+		trace = append(trace, "handler")
+		return nil
+	}}
+
+	c := NewContext()
+	c.Reset(&protocol.Proto{ServeMethod: "echo"}, &protocol.Proto{})
+	if err := discover.handler(md)(c); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(trace, " "); got != "global> service> method> handler <method <service after <global" {
+		t.Errorf("chain order got %s", got)
+	}
+
+	// 处理失败时不执行after，panic转为错误
+	trace = nil
+	md.Handler = func(srv interface{}, c Context) error { panic("boom") }
+	if err := discover.handler(md)(c); err == nil || errStatus(err) != 500 {
+		t.Errorf("panic should be recovered,%v", err)
+	}
+	for _, s := range trace {
+		if s == "after" {
+			t.Errorf("after should not run when handler fail")
+		}
+	}
+	if st := MethodStats()["echo"]; st.Count != 2 || st.Fail != 1 || st.Panic != 1 {
+		t.Errorf("stat got %+v", st)
+	}
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	c := NewContext()
+	c.Reset(&protocol.Proto{}, &protocol.Proto{})
+	h := chainInterceptors([]Interceptor{Timeout(10 * time.Millisecond)}, func(c Context) error {
+		<-c.Ctx().Done()
+		return nil
+	})
+	if err := h(c); err != ErrTimeout || errStatus(err) != 504 {
+		t.Errorf("should time out,%v", err)
+	}
+	if c.Ctx().Err() != nil {
+		t.Errorf("ctx should be restored after timeout interceptor")
+	}
+
+	// 超时后返回的错误也转为ErrTimeout
+	h = chainInterceptors([]Interceptor{Timeout(10 * time.Millisecond)}, func(c Context) error {
+		<-c.Ctx().Done()
+		return c.Ctx().Err()
+	})
+	if err := h(c); err != ErrTimeout {
+		t.Errorf("handler error after deadline should be ErrTimeout,%v", err)
+	}
+}
+
+func TestClientInterceptorChain(t *testing.T) {
+	var trace []string
+	ic := func(name string) ClientInterceptor {
+		return func(task *protocol.Proto, invoker Invoker, opts ...CallOption) (*protocol.Proto, error) {
+			trace = append(trace, name)
+			return invoker(task, opts...)
+		}
+	}
+	invoker := chainClientInterceptors([]ClientInterceptor{ic("a"), ic("b")},
+		func(task *protocol.Proto, opts ...CallOption) (*protocol.Proto, error) {
+			trace = append(trace, "deliver")
+			return nil, errors.New("fail")
+		})
+	if _, err := invoker(&protocol.Proto{}); err == nil || strings.Join(trace, " ") != "a b deliver" {
+		t.Errorf("client chain got %v %v", trace, err)
+	}
+}
@@ -3,7 +3,6 @@ package frame
 import (
 	"bytes"
 	"encoding/binary"
-	log "github.com/kwins/iceberg/frame/icelog"
 	"github.com/kwins/iceberg/frame/protocol"
 	"io"
 	"net"
@@ -25,12 +24,12 @@ func RecvPack(conn net.Conn) ([]byte, error) {
 		n, err := conn.Read(buf[0:])
 		if err != nil {
 			if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
-				log.Error("Read timeout")
+				connLog.Error("Read timeout")
 			} else if err == io.EOF {
 				// 连接断开
-				log.Error("Connection break!")
+				connLog.Error("Connection break!")
 			} else {
-				log.Error("Read from connection failed!, detail:", err.Error())
+				connLog.Error("Read from connection failed!, detail:", err.Error())
 			}
 
 			conn.Close()
@@ -41,7 +40,7 @@ func RecvPack(conn net.Conn) ([]byte, error) {
 		recvBytes := recvedBuf.Len()
 		if recvBytes < protocol.HeaderLength {
 			// 从TCP流中读取数据太少继续读
-			log.Info("Keep recv...")
+			connLog.Info("Keep recv...")
 			continue
 		}
 
@@ -50,12 +49,12 @@ func RecvPack(conn net.Conn) ([]byte, error) {
 		binary.Read(leaderNumBuf, binary.BigEndian, &length)
 		if recvBytes < int(length) {
 			// 从TCP流中读取数据还是太少,继续读
-			log.Warn("Pack head shows size=%d, buf just recv %d bytes, keep receive.", length, recvBytes)
+			connLog.Warn("Pack head shows size=%d, buf just recv %d bytes, keep receive.", length, recvBytes)
 			continue
 		}
 
 		if recvBytes > int(length) {
-			log.Error("Recv data much than a pack. this issue is unnormal in iceberg!!")
+			connLog.Error("Recv data much than a pack. this issue is unnormal in iceberg!!")
 		}
 
 		// 读到了完整的包,暂停读数据
@@ -84,7 +83,7 @@ func ContinuousRecvPack(conn net.Conn, cstmFunc ProcessInComingPackFunc) {
 				}
 				continue
 			}
-			log.Warnf("Read from connection failed!, detail=%s", err.Error())
+			connLog.Warnf("Read from connection failed!, detail=%s", err.Error())
 			go cstmFunc(nil) // notice handler connection is broken.
 			return
 		}
@@ -92,7 +91,7 @@ func ContinuousRecvPack(conn net.Conn, cstmFunc ProcessInComingPackFunc) {
 		recvedBuf.Write(buf[0:n])
 		if recvedBuf.Len() < protocol.HeaderLength {
 			// 从TCP流中读取数据太少继续读
-			log.Info("Keep recv...")
+			connLog.Info("Keep recv...")
 			continue
 		}
 
@@ -103,7 +102,7 @@ func ContinuousRecvPack(conn net.Conn, cstmFunc ProcessInComingPackFunc) {
 			length = uint32(headByte[3]) | uint32(headByte[2])<<8 | uint32(headByte[1])<<16 | uint32(headByte[0])<<24
 			if recvedBuf.Len() < int(length) {
 				// 从TCP流中读取数据还是太少,继续读
-				log.Debugf("Pack head shows size=%d, buf just recv %d bytes, keep receive.", length, recvedBuf.Len())
+				connLog.Debugf("Pack head shows size=%d, buf just recv %d bytes, keep receive.", length, recvedBuf.Len())
 				break
 			}
 			pack := make([]byte, length)
@@ -121,7 +120,7 @@ func SendAll(conn net.Conn, buf []byte) int {
 		n, err := conn.Write(buf[sentbytes:])
 		sentbytes += n
 		if err != nil {
-			log.Errorf("Failed send data to %s detail=%s", conn.RemoteAddr().String(), err)
+			connLog.Errorf("Failed send data to %s detail=%s", conn.RemoteAddr().String(), err)
 			if err == io.EOF {
 				return sentbytes
 			}
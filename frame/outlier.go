@@ -0,0 +1,288 @@
+package frame
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 异常实例检测
+// 被动检测: 记录每次调用的结果，连续失败，连续超时，错误率过高或者延迟明显高于
+// 同组实例的节点会被暂时摘除，摘除时间随摘除次数指数增长;
+// 主动检测: 定时对实例发起TCP连接，连接失败的实例同样被摘除;
+// 同一服务被摘除的实例数不超过MaxEjectionPercent，避免雪崩
+
+type instanceStat struct {
+	requests    int64         // 本周期请求数
+	failures    int64         // 本周期失败数
+	latency     time.Duration // 本周期总延迟
+	consecFail  int           // 连续失败次数
+	consecTmout int           // 连续超时次数
+
+	ejectedUntil time.Time // 摘除截止时间
+	ejectTimes   uint      // 摘除次数，决定下一次摘除时长
+}
+
+// OutlierDetector 异常实例检测器
+type OutlierDetector struct {
+	cfg    config.OutlierCfg
+	locker sync.Mutex
+	stats  map[string]*instanceStat       // key是实例地址
+	groups map[string]map[string]struct{} // key是服务URI
+}
+
+// NewOutlierDetector new outlier detector
+func NewOutlierDetector(cfg config.OutlierCfg) *OutlierDetector {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10
+	}
+	if cfg.ConsecutiveErrors <= 0 {
+		cfg.ConsecutiveErrors = 5
+	}
+	if cfg.ConsecutiveTimeouts <= 0 {
+		cfg.ConsecutiveTimeouts = 3
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.BaseEjection <= 0 {
+		cfg.BaseEjection = 30
+	}
+	if cfg.MaxEjection <= 0 {
+		cfg.MaxEjection = 300
+	}
+	if cfg.MaxEjectionPercent <= 0 {
+		cfg.MaxEjectionPercent = 50
+	}
+	if cfg.CheckTimeout <= 0 {
+		cfg.CheckTimeout = 500
+	}
+	od := new(OutlierDetector)
+	od.cfg = cfg
+	od.stats = make(map[string]*instanceStat)
+	od.groups = make(map[string]map[string]struct{})
+	return od
+}
+
+// Record 记录一次调用结果
+// URI 服务URI; svrAddr 实例地址; cost 调用耗时; resp, err 调用结果
+func (od *OutlierDetector) Record(URI, svrAddr string, cost time.Duration, resp *protocol.Proto, err error) {
+	if od == nil || svrAddr == "" {
+		return
+	}
+	failed := err != nil || serverFailed(resp)
+	timeout := err == ErrTimeout
+
+	od.locker.Lock()
+	defer od.locker.Unlock()
+	st := od.stat(URI, svrAddr)
+	st.requests++
+	st.latency += cost
+	if !failed {
+		st.consecFail, st.consecTmout = 0, 0
+		return
+	}
+	st.failures++
+	st.consecFail++
+	if timeout {
+		st.consecTmout++
+	} else {
+		st.consecTmout = 0
+	}
+	if st.consecFail >= od.cfg.ConsecutiveErrors {
+		od.eject(URI, svrAddr, "consecutive errors")
+	} else if st.consecTmout >= od.cfg.ConsecutiveTimeouts {
+		od.eject(URI, svrAddr, "consecutive timeouts")
+	}
+}
+
+// Ejected 实例当前是否被摘除
+func (od *OutlierDetector) Ejected(svrAddr string) bool {
+	if od == nil {
+		return false
+	}
+	od.locker.Lock()
+	defer od.locker.Unlock()
+	if st, ok := od.stats[svrAddr]; ok {
+		return time.Now().Before(st.ejectedUntil)
+	}
+	return false
+}
+
+// Forget 实例下线后清除统计信息
+func (od *OutlierDetector) Forget(svrAddr string) {
+	if od == nil {
+		return
+	}
+	od.locker.Lock()
+	delete(od.stats, svrAddr)
+	for _, group := range od.groups {
+		delete(group, svrAddr)
+	}
+	od.locker.Unlock()
+}
+
+func (od *OutlierDetector) stat(URI, svrAddr string) *instanceStat {
+	st, ok := od.stats[svrAddr]
+	if !ok {
+		st = new(instanceStat)
+		od.stats[svrAddr] = st
+	}
+	if URI != "" {
+		group, ok := od.groups[URI]
+		if !ok {
+			group = make(map[string]struct{})
+			od.groups[URI] = group
+		}
+		group[svrAddr] = struct{}{}
+	}
+	return st
+}
+
+// eject 摘除实例，调用方需持有锁
+func (od *OutlierDetector) eject(URI, svrAddr, reason string) bool {
+	now := time.Now()
+	st := od.stats[svrAddr]
+	if now.Before(st.ejectedUntil) {
+		return false
+	}
+	if group := od.groups[URI]; len(group) > 0 {
+		var ejected int
+		for addr := range group {
+			if s := od.stats[addr]; s != nil && now.Before(s.ejectedUntil) {
+				ejected++
+			}
+		}
+		if (ejected+1)*100 > len(group)*od.cfg.MaxEjectionPercent {
+			outlierLog.Warnf("iceberg:%s of %s should be ejected(%s),but reach max ejection percent",
+				svrAddr, URI, reason)
+			return false
+		}
+	}
+
+	st.ejectTimes++
+	d := time.Duration(od.cfg.BaseEjection) * time.Second << (st.ejectTimes - 1)
+	if max := time.Duration(od.cfg.MaxEjection) * time.Second; d > max || d <= 0 {
+		d = max
+	}
+	st.ejectedUntil = now.Add(d)
+	st.consecFail, st.consecTmout = 0, 0
+	outlierLog.Warnf("iceberg:eject %s of %s for %s,reason:%s", svrAddr, URI, d, reason)
+	return true
+}
+
+// evaluate 周期性按错误率和延迟检测异常实例，并重置统计周期
+func (od *OutlierDetector) evaluate() {
+	od.locker.Lock()
+	defer od.locker.Unlock()
+	now := time.Now()
+	for URI, group := range od.groups {
+		var latencies []time.Duration
+		for addr := range group {
+			st := od.stats[addr]
+			if st.requests >= int64(od.cfg.MinRequests) {
+				latencies = append(latencies, st.latency/time.Duration(st.requests))
+			}
+		}
+		var median time.Duration
+		if len(latencies) >= 3 {
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			median = latencies[len(latencies)/2]
+		}
+
+		for addr := range group {
+			st := od.stats[addr]
+			if st.requests >= int64(od.cfg.MinRequests) {
+				if od.cfg.ErrorPercent > 0 &&
+					st.failures*100 >= st.requests*int64(od.cfg.ErrorPercent) {
+					od.eject(URI, addr, "error percent")
+				} else if od.cfg.LatencyFactor > 0 && median > 0 &&
+					float64(st.latency/time.Duration(st.requests)) > od.cfg.LatencyFactor*float64(median) {
+					od.eject(URI, addr, "latency outlier")
+				}
+			}
+			// 健康运行一个周期后逐步恢复摘除时长
+			if now.After(st.ejectedUntil) && st.ejectTimes > 0 && st.failures == 0 {
+				st.ejectTimes--
+			}
+			st.requests, st.failures, st.latency = 0, 0, 0
+		}
+	}
+}
+
+// check 主动检测实例是否能建立TCP连接
+func (od *OutlierDetector) check(URI, svrAddr string) {
+	c, err := net.DialTimeout("tcp", svrAddr, time.Duration(od.cfg.CheckTimeout)*time.Millisecond)
+	if err != nil {
+		od.locker.Lock()
+		od.stat(URI, svrAddr)
+		od.eject(URI, svrAddr, "health check:"+err.Error())
+		od.locker.Unlock()
+		return
+	}
+	c.Close()
+}
+
+// serverFailed 响应是否为服务端错误
+func serverFailed(resp *protocol.Proto) bool {
+	if resp == nil || len(resp.GetErr()) == 0 {
+		return false
+	}
+	var info protocol.ErrInfo
+	if err := json.Unmarshal(resp.GetErr(), &info); err != nil {
+		return true
+	}
+	return info.ErrCode >= 500
+}
+
+// startOutlierDetect 开启异常实例检测
+func (discover *Discover) startOutlierDetect(cfg config.OutlierCfg) {
+	if !cfg.Enable {
+		return
+	}
+	discover.outlier = NewOutlierDetector(cfg)
+	go func() {
+		od := discover.outlier
+		evaluate := time.NewTicker(time.Duration(od.cfg.Interval) * time.Second)
+		defer evaluate.Stop()
+		var check <-chan time.Time
+		if od.cfg.HealthCheck && od.cfg.CheckInterval > 0 {
+			t := time.NewTicker(time.Duration(od.cfg.CheckInterval) * time.Second)
+			defer t.Stop()
+			check = t.C
+		}
+		for {
+			select {
+			case <-evaluate.C:
+				od.evaluate()
+			case <-check:
+				discover.topoLocker.RLock()
+				var targets = make(map[string][]string)
+				for URI, node := range discover.topology {
+					node.RLock()
+					targets[URI] = node.AllNode()
+					node.RUnlock()
+				}
+				discover.topoLocker.RUnlock()
+				for URI, addrs := range targets {
+					for _, addr := range addrs {
+						od.check(URI, addr)
+					}
+				}
+			case <-discover.ctx.Done():
+				return
+			}
+		}
+	}()
+	outlierLog.Infof("iceberg:outlier detection enabled,cfg=%+v", discover.outlier.cfg)
+}
+
+// available 实例没有被摘除
+func (discover *Discover) available(svrAddr string) bool {
+	return !discover.outlier.Ejected(svrAddr)
+}
@@ -0,0 +1,37 @@
+package frame
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+)
+
+func TestOutlierEject(t *testing.T) {
+	od := NewOutlierDetector(config.OutlierCfg{Enable: true, ConsecutiveErrors: 3})
+	uri := "/services/v1/hello"
+	for _, addr := range []string{"10.0.0.1:1", "10.0.0.2:1", "10.0.0.3:1"} {
+		od.Record(uri, addr, time.Millisecond, nil, nil)
+	}
+
+	for i := 0; i < 3; i++ {
+		od.Record(uri, "10.0.0.1:1", time.Millisecond, nil, errors.New("broken"))
+	}
+	if !od.Ejected("10.0.0.1:1") {
+		t.Error("10.0.0.1:1 should be ejected")
+	}
+
+	// 超过最大摘除比例后不再摘除
+	for i := 0; i < 3; i++ {
+		od.Record(uri, "10.0.0.2:1", time.Millisecond, nil, ErrTimeout)
+	}
+	if od.Ejected("10.0.0.2:1") {
+		t.Error("10.0.0.2:1 should not be ejected, max ejection percent")
+	}
+
+	od.Forget("10.0.0.1:1")
+	if od.Ejected("10.0.0.1:1") {
+		t.Error("10.0.0.1:1 should be forgot")
+	}
+}
@@ -62,6 +62,8 @@ func init() {
 // plugin architecture.  It generates bindings for gRPC support.
 type irpc struct {
 	gen *generator.Generator
+	// validate 当前文件是否生成了Validate方法
+	validate bool
 }
 
 // Name returns the name of this plugin, "irpc".
@@ -99,6 +101,12 @@ func (ig *irpc) P(args ...interface{}) { ig.gen.P(args...) }
 
 // Generate generates code for the services in the given file.
 func (ig *irpc) Generate(file *generator.FileDescriptor) {
+	msgs := ig.validateMessages(file)
+	ig.validate = len(msgs) > 0
+	if ig.validate {
+		ig.generateValidators(file, msgs)
+	}
+
 	if len(file.FileDescriptorProto.Service) == 0 {
 		return
 	}
@@ -120,14 +128,20 @@ func (ig *irpc) Generate(file *generator.FileDescriptor) {
 
 // GenerateImports generates the import declaration for this file.
 func (ig *irpc) GenerateImports(file *generator.FileDescriptor) {
-	if len(file.FileDescriptorProto.Service) == 0 {
+	hasService := len(file.FileDescriptorProto.Service) > 0
+	if !hasService && !ig.validate {
 		return
 	}
 	ig.P("import (")
-	ig.P(strconv.Quote("context"))
-	ig.P(strconv.Quote("github.com/kwins/iceberg/frame"))
-	ig.P(strconv.Quote("github.com/kwins/iceberg/frame/config"))
-	ig.P(strconv.Quote("github.com/kwins/iceberg/frame/protocol"))
+	if hasService {
+		ig.P(strconv.Quote("context"))
+		ig.P(strconv.Quote("github.com/kwins/iceberg/frame"))
+		ig.P(strconv.Quote("github.com/kwins/iceberg/frame/config"))
+		ig.P(strconv.Quote("github.com/kwins/iceberg/frame/protocol"))
+	}
+	if ig.validate {
+		ig.P(strconv.Quote("github.com/kwins/iceberg/frame/validate"))
+	}
 	ig.P(")")
 	ig.P()
 }
@@ -169,7 +183,7 @@ func (ig *irpc) generateService(file *generator.FileDescriptor, service *pb.Serv
 		ig.P("	return nil, err")
 		ig.P("}")
 
-		ig.P("back, err := frame.DeliverTo(task)")
+		ig.P("back, err := frame.DeliverTo(task, opts...)")
 		ig.P("if err != nil {")
 		ig.P("	return nil, err")
 		ig.P("}")
@@ -213,7 +227,11 @@ func (ig *irpc) generateService(file *generator.FileDescriptor, service *pb.Serv
 	ig.P("HandlerType:", "(*", servName, "Server)(nil),")
 	ig.P("Methods: []frame.MethodDesc{")
 
-	for _, method := range service.Method {
+	for i, method := range service.Method {
+		methods, err := parseMethods(locationComments(file, []int32{6, int32(index), 2, int32(i)}))
+		if err != nil {
+			ig.gen.Fail("invalid @methods of", servName+"."+method.GetName(), err.Error())
+		}
 		ig.P("{")
 		if method.GetClientStreaming() || method.GetServerStreaming() {
 			ig.P("Allowed: ", strconv.Quote("true"), ",")
@@ -221,6 +239,13 @@ func (ig *irpc) generateService(file *generator.FileDescriptor, service *pb.Serv
 			ig.P("Allowed: ", strconv.Quote("false"), ",")
 		}
 		ig.P("MethodName: ", strconv.Quote(strings.ToLower(method.GetName())), ",")
+		if len(methods) > 0 {
+			quoted := make([]string, len(methods))
+			for j, m := range methods {
+				quoted[j] = strconv.Quote(m)
+			}
+			ig.P("HTTPMethods: []string{", strings.Join(quoted, ", "), "},")
+		}
 		ig.P("Handler: ", unexport(servName)+method.GetName()+"Handler,")
 		ig.P("},")
 	}
@@ -2,8 +2,49 @@ package irpc
 
 import "testing"
 import "strconv"
+import "strings"
 
 func TestQuote(t *testing.T) {
 	t.Log(strconv.Quote(`aaaaaaa
 		`))
 }
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(" 用户名\n @validate required min_len=2 pattern=`^[a-z ]+$` in=\"a b\\\"c\"\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fieldRule{
+		{name: "required"},
+		{name: "min_len", value: "2"},
+		{name: "pattern", value: "^[a-z ]+$"},
+		{name: "in", value: `a b"c`},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("want %v,got %v", want, rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d want %v,got %v", i, want[i], rules[i])
+		}
+	}
+	if _, err := parseRules("@validate pattern=`abc"); err == nil {
+		t.Error("unterminated value should fail")
+	}
+}
+
+func TestParseMethods(t *testing.T) {
+	methods, err := parseMethods(" 查询用户\n @methods get, POST patch get\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(methods, ",") != "GET,POST,PATCH" {
+		t.Errorf("want GET,POST,PATCH,got %v", methods)
+	}
+	if methods, _ := parseMethods(" 没有声明\n"); len(methods) != 0 {
+		t.Errorf("want no methods,got %v", methods)
+	}
+	if _, err := parseMethods("@methods GET,TRACE"); err == nil {
+		t.Error("unknown method should fail")
+	}
+}
@@ -0,0 +1,55 @@
+package irpc
+
+// 根据方法注释中的 @methods 声明生成 MethodDesc.HTTPMethods，Gateway对未声明的HTTP方法返回405
+//
+//	service Hello {
+//		// @methods GET,POST
+//		rpc SayHello(HelloRequest) returns (HelloResponse) {}
+//	}
+//
+// 多个方法使用逗号或空白分隔，没有声明时不限制;
+// 声明了GET时也接受HEAD，OPTIONS总是由Gateway直接响应
+
+import (
+	"fmt"
+	"strings"
+)
+
+const methodsAnnotation = "@methods"
+
+// httpMethods 可以声明的HTTP方法，与protocol.RestfulMethod一致
+var httpMethods = map[string]bool{
+	"POST":    true,
+	"PUT":     true,
+	"GET":     true,
+	"DELETE":  true,
+	"PATCH":   true,
+	"HEAD":    true,
+	"OPTIONS": true,
+}
+
+// parseMethods 解析注释中的 @methods 声明
+func parseMethods(comments string) ([]string, error) {
+	var methods []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(comments, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, methodsAnnotation) {
+			continue
+		}
+		fields := strings.FieldsFunc(line[len(methodsAnnotation):], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, m := range fields {
+			m = strings.ToUpper(m)
+			if !httpMethods[m] {
+				return nil, fmt.Errorf("unknown http method %q", m)
+			}
+			if !seen[m] {
+				seen[m] = true
+				methods = append(methods, m)
+			}
+		}
+	}
+	return methods, nil
+}
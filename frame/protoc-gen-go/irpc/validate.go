@@ -0,0 +1,370 @@
+package irpc
+
+// 根据字段注释中的 @validate 规则生成 Validate() error 方法
+//
+//	message HelloRequest {
+//		// @validate required min_len=2 max_len=32
+//		string name = 1;
+//		int32 age = 2; // @validate min=1 max=150
+//		string email = 3; // @validate email
+//		string lang = 4; // @validate in=zh,en
+//		string code = 5; // @validate pattern=`^[0-9]{6}$`
+//		Color color = 6; // @validate enum
+//	}
+//
+// 规则:
+// required        字符串，bytes，数组，map非空，数字，枚举非0，消息不为nil
+// min,max         数字的取值范围
+// len,min_len,max_len 字符串的字符数，bytes的长度，数组和map的元素个数
+// pattern         字符串匹配正则表达式，值使用双引号(支持转义)或反引号，不合法的正则表达式生成时报错
+// email           字符串是合法的邮箱地址
+// in              字符串或数字是给定值之一，多个值使用逗号分隔
+// enum            枚举值是已定义的值
+// 除required外的规则在字段为零值时不检查;消息类型的字段总是递归校验，oneof中的字段不校验
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pb "github.com/kwins/iceberg/frame/protoc-gen-go/descriptor"
+	"github.com/kwins/iceberg/frame/protoc-gen-go/generator"
+)
+
+const validateAnnotation = "@validate"
+
+type fieldRule struct {
+	name  string
+	value string
+}
+
+// parseRules 解析注释中的 @validate 规则
+func parseRules(comments string) ([]fieldRule, error) {
+	var rules []fieldRule
+	for _, line := range strings.Split(comments, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, validateAnnotation) {
+			continue
+		}
+		tokens, err := splitRules(strings.TrimSpace(line[len(validateAnnotation):]))
+		if err != nil {
+			return nil, err
+		}
+		for _, tok := range tokens {
+			var r fieldRule
+			if i := strings.IndexByte(tok, '='); i != -1 {
+				r.name, r.value = tok[:i], tok[i+1:]
+			} else {
+				r.name = tok
+			}
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+// splitRules 按空白分隔规则，规则的值可以使用双引号或反引号
+func splitRules(s string) ([]string, error) {
+	var tokens []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		i := strings.IndexAny(s, " \t=")
+		if i == -1 || s[i] != '=' {
+			if i == -1 {
+				i = len(s)
+			}
+			tokens = append(tokens, s[:i])
+			s = s[i:]
+			continue
+		}
+		name, rest := s[:i+1], s[i+1:]
+		if rest == "" || (rest[0] != '"' && rest[0] != '`') {
+			j := strings.IndexAny(rest, " \t")
+			if j == -1 {
+				j = len(rest)
+			}
+			tokens = append(tokens, name+rest[:j])
+			s = rest[j:]
+			continue
+		}
+		quote := rest[0]
+		end := 1
+		for ; end < len(rest); end++ {
+			if rest[end] == '\\' && quote == '"' {
+				end++
+				continue
+			}
+			if rest[end] == quote {
+				break
+			}
+		}
+		if end >= len(rest) {
+			return nil, fmt.Errorf("unterminated quoted value in %q", s)
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("bad quoted value %s,%s", rest[:end+1], err.Error())
+		}
+		tokens = append(tokens, name+value)
+		s = rest[end+1:]
+	}
+	return tokens, nil
+}
+
+// fieldComments 字段的注释，path为字段在SourceCodeInfo中的路径
+func locationComments(file *generator.FileDescriptor, path []int32) string {
+	for _, loc := range file.GetSourceCodeInfo().GetLocation() {
+		lp := loc.GetPath()
+		if len(lp) != len(path) {
+			continue
+		}
+		match := true
+		for i := range lp {
+			if lp[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return loc.GetLeadingComments() + "\n" + loc.GetTrailingComments()
+		}
+	}
+	return ""
+}
+
+type validateMessage struct {
+	typeName string
+	desc     *pb.DescriptorProto
+	path     []int32
+}
+
+// validateMessages 文件中需要生成Validate方法的消息，没有任何校验规则时返回空
+func (ig *irpc) validateMessages(file *generator.FileDescriptor) []validateMessage {
+	var msgs []validateMessage
+	var walk func(prefix string, descs []*pb.DescriptorProto, path []int32)
+	walk = func(prefix string, descs []*pb.DescriptorProto, path []int32) {
+		for i, desc := range descs {
+			if desc.GetOptions().GetMapEntry() {
+				continue
+			}
+			p := append(append([]int32{}, path...), int32(i))
+			name := prefix + "." + desc.GetName()
+			msgs = append(msgs, validateMessage{typeName: name, desc: desc, path: p})
+			walk(name, desc.NestedType, append(append([]int32{}, p...), 3)) // 3 means nested message
+		}
+	}
+	prefix := ""
+	if pkg := file.GetPackage(); pkg != "" {
+		prefix = "." + pkg
+	}
+	walk(prefix, file.MessageType, []int32{4}) // 4 means message
+
+	for _, msg := range msgs {
+		for j := range msg.desc.Field {
+			if strings.Contains(locationComments(file, append(append([]int32{}, msg.path...), 2, int32(j))), validateAnnotation) {
+				return msgs
+			}
+		}
+	}
+	return nil
+}
+
+// generateValidators 为消息生成Validate方法
+func (ig *irpc) generateValidators(file *generator.FileDescriptor, msgs []validateMessage) {
+	for _, msg := range msgs {
+		goName := ig.typeName(msg.typeName)
+		ig.P()
+		ig.P("// Validate 校验", goName, "的字段")
+		ig.P("func (m *", goName, ") Validate() error {")
+		ig.P("if m == nil {")
+		ig.P("	return nil")
+		ig.P("}")
+		ig.P("var errs validate.Errors")
+		for j, field := range msg.desc.Field {
+			if field.OneofIndex != nil {
+				continue
+			}
+			comments := locationComments(file, append(append([]int32{}, msg.path...), 2, int32(j)))
+			rules, err := parseRules(comments)
+			if err != nil {
+				ig.gen.Fail("invalid @validate rule of", msg.typeName, field.GetName(), err.Error())
+			}
+			ig.generateFieldRules(msg.desc, field, rules)
+		}
+		ig.P("return errs.Err()")
+		ig.P("}")
+	}
+}
+
+func (ig *irpc) generateFieldRules(msg *pb.DescriptorProto, field *pb.FieldDescriptorProto, rules []fieldRule) {
+	name := field.GetName()
+	v := "m." + generator.CamelCase(name)
+	quoted := strconv.Quote(name)
+	add := func(rule, msgFormat string, args ...interface{}) {
+		ig.P("	errs.Add(", quoted, ", ", strconv.Quote(rule), ", ", strconv.Quote(fmt.Sprintf(msgFormat, args...)), ")")
+	}
+	fail := func(rule string) {
+		ig.gen.Fail("@validate rule", rule, "is not supported by field", msg.GetName()+"."+name)
+	}
+
+	repeated := field.GetLabel() == pb.FieldDescriptorProto_LABEL_REPEATED
+	kind := fieldKind(field)
+	if repeated {
+		kind = kindList
+	}
+
+	var zero, nonzero string
+	switch kind {
+	case kindString:
+		zero, nonzero = v+` == ""`, v+` != ""`
+	case kindBytes, kindList:
+		zero, nonzero = "len("+v+") == 0", "len("+v+") > 0"
+	case kindNumber, kindEnum:
+		zero, nonzero = v+" == 0", v+" != 0"
+	case kindMessage:
+		zero, nonzero = v+" == nil", v+" != nil"
+	case kindBool:
+		zero, nonzero = "!"+v, v
+	}
+
+	var optional []fieldRule
+	for _, r := range rules {
+		if r.name == "required" {
+			ig.P("if ", zero, " {")
+			add("required", "不能为空")
+			ig.P("}")
+			continue
+		}
+		optional = append(optional, r)
+	}
+	if len(optional) > 0 {
+		ig.P("if ", nonzero, " {")
+		for _, r := range optional {
+			switch r.name {
+			case "min", "max":
+				if kind != kindNumber {
+					fail(r.name)
+				}
+				op, text := "<", "不能小于"
+				if r.name == "max" {
+					op, text = ">", "不能大于"
+				}
+				ig.P("if ", v, " ", op, " ", r.value, " {")
+				add(r.name, "%s%s", text, r.value)
+				ig.P("}")
+			case "len", "min_len", "max_len":
+				n, err := strconv.Atoi(r.value)
+				if err != nil {
+					ig.gen.Fail("@validate", r.name, "of", msg.GetName()+"."+name, "must be an integer")
+				}
+				length := "len(" + v + ")"
+				if kind == kindString {
+					length = "validate.RuneLen(" + v + ")"
+				} else if kind != kindBytes && kind != kindList {
+					fail(r.name)
+				}
+				op, text := "!=", "长度必须为"
+				if r.name == "min_len" {
+					op, text = "<", "长度不能小于"
+				} else if r.name == "max_len" {
+					op, text = ">", "长度不能大于"
+				}
+				ig.P("if ", length, " ", op, " ", n, " {")
+				add(r.name, "%s%d", text, n)
+				ig.P("}")
+			case "pattern":
+				if kind != kindString {
+					fail(r.name)
+				}
+				if _, err := regexp.Compile(r.value); err != nil {
+					ig.gen.Fail("@validate pattern of", msg.GetName()+"."+name, "is invalid:", err.Error())
+				}
+				ig.P("if !validate.Match(", strconv.Quote(r.value), ", ", v, ") {")
+				add(r.name, "格式不正确")
+				ig.P("}")
+			case "email":
+				if kind != kindString {
+					fail(r.name)
+				}
+				ig.P("if !validate.IsEmail(", v, ") {")
+				add(r.name, "不是合法的邮箱")
+				ig.P("}")
+			case "in":
+				var cases []string
+				for _, e := range strings.Split(r.value, ",") {
+					if kind == kindString {
+						e = strconv.Quote(e)
+					} else if kind != kindNumber && kind != kindEnum {
+						fail(r.name)
+					}
+					cases = append(cases, e)
+				}
+				ig.P("switch ", v, " {")
+				ig.P("case ", strings.Join(cases, ", "), ":")
+				ig.P("default:")
+				add(r.name, "必须是%s之一", r.value)
+				ig.P("}")
+			case "enum":
+				if kind != kindEnum {
+					fail(r.name)
+				}
+				ig.P("if _, ok := ", ig.typeName(field.GetTypeName()), "_name[int32(", v, ")]; !ok {")
+				add(r.name, "不是合法的枚举值")
+				ig.P("}")
+			default:
+				ig.gen.Fail("unknown @validate rule", r.name, "of", msg.GetName()+"."+name)
+			}
+		}
+		ig.P("}")
+	}
+
+	// 消息类型的字段递归校验
+	if field.GetType() != pb.FieldDescriptorProto_TYPE_MESSAGE || ig.isMapField(field) {
+		return
+	}
+	if repeated {
+		ig.P("for i, e := range ", v, " {")
+		ig.P("	errs.Nested(validate.Index(", quoted, ", i), validate.Check(e))")
+		ig.P("}")
+	} else {
+		ig.P("errs.Nested(", quoted, ", validate.Check(", v, "))")
+	}
+}
+
+const (
+	kindString = iota
+	kindBytes
+	kindNumber
+	kindBool
+	kindEnum
+	kindMessage
+	kindList
+)
+
+func fieldKind(field *pb.FieldDescriptorProto) int {
+	switch field.GetType() {
+	case pb.FieldDescriptorProto_TYPE_STRING:
+		return kindString
+	case pb.FieldDescriptorProto_TYPE_BYTES:
+		return kindBytes
+	case pb.FieldDescriptorProto_TYPE_BOOL:
+		return kindBool
+	case pb.FieldDescriptorProto_TYPE_ENUM:
+		return kindEnum
+	case pb.FieldDescriptorProto_TYPE_MESSAGE, pb.FieldDescriptorProto_TYPE_GROUP:
+		return kindMessage
+	default:
+		return kindNumber
+	}
+}
+
+// isMapField map类型的字段
+func (ig *irpc) isMapField(field *pb.FieldDescriptorProto) bool {
+	if field.GetLabel() != pb.FieldDescriptorProto_LABEL_REPEATED {
+		return false
+	}
+	if d, ok := ig.objectNamed(field.GetTypeName()).(*generator.Descriptor); ok {
+		return d.GetOptions().GetMapEntry()
+	}
+	return false
+}
@@ -9,6 +9,8 @@ It is generated from these files:
 
 It has these top-level messages:
 	Proto
+	FormFile
+	Values
 */
 package protocol
 
@@ -35,6 +37,9 @@ const (
 	RestfulMethod_PUT        RestfulMethod = 2
 	RestfulMethod_GET        RestfulMethod = 3
 	RestfulMethod_DELETE     RestfulMethod = 4
+	RestfulMethod_PATCH      RestfulMethod = 5
+	RestfulMethod_HEAD       RestfulMethod = 6
+	RestfulMethod_OPTIONS    RestfulMethod = 7
 )
 
 var RestfulMethod_name = map[int32]string{
@@ -43,6 +48,9 @@ var RestfulMethod_name = map[int32]string{
 	2: "PUT",
 	3: "GET",
 	4: "DELETE",
+	5: "PATCH",
+	6: "HEAD",
+	7: "OPTIONS",
 }
 var RestfulMethod_value = map[string]int32{
 	"METHODNULL": 0,
@@ -50,6 +58,9 @@ var RestfulMethod_value = map[string]int32{
 	"PUT":        2,
 	"GET":        3,
 	"DELETE":     4,
+	"PATCH":      5,
+	"HEAD":       6,
+	"OPTIONS":    7,
 }
 
 func (x RestfulMethod) String() string {
@@ -112,6 +123,14 @@ type Proto struct {
 	Body []byte `protobuf:"bytes,11,opt,name=Body,proto3" json:"Body" xml:"Body,omitempty"`
 	// 响应错误信息，Body 和 Err 互斥
 	Err []byte `protobuf:"bytes,12,opt,name=Err,proto3" json:"Err" xml:"Err,omitempty"`
+	// multipart表单中上传的文件
+	Files []*FormFile `protobuf:"bytes,13,rep,name=Files" json:"Files" xml:"Files,omitempty"`
+	// 响应的HTTP状态码，为0时由gateway决定
+	Status int32 `protobuf:"varint,14,opt,name=Status" json:"Status" xml:"Status,omitempty"`
+	// 多值Header，包含该Header的所有值，优先于Header中的同名Header
+	HeaderValues map[string]*Values `protobuf:"bytes,15,rep,name=HeaderValues" json:"HeaderValues" xml:"HeaderValues,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// 多值Form，包含该参数的所有值，优先于Form中的同名参数
+	FormValues map[string]*Values `protobuf:"bytes,16,rep,name=FormValues" json:"FormValues" xml:"FormValues,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 }
 
 func (m *Proto) Reset()                    { *m = Proto{} }
@@ -203,8 +222,109 @@ func (m *Proto) GetErr() []byte {
 	return nil
 }
 
+func (m *Proto) GetFiles() []*FormFile {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+func (m *Proto) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *Proto) GetHeaderValues() map[string]*Values {
+	if m != nil {
+		return m.HeaderValues
+	}
+	return nil
+}
+
+func (m *Proto) GetFormValues() map[string]*Values {
+	if m != nil {
+		return m.FormValues
+	}
+	return nil
+}
+
+// FormFile 上传的文件
+type FormFile struct {
+	// 表单字段名称
+	Field string `protobuf:"bytes,1,opt,name=Field" json:"Field" xml:"Field,omitempty"`
+	// 文件名称
+	Filename string `protobuf:"bytes,2,opt,name=Filename" json:"Filename" xml:"Filename,omitempty"`
+	// 文件类型
+	ContentType string `protobuf:"bytes,3,opt,name=ContentType" json:"ContentType" xml:"ContentType,omitempty"`
+	// 文件大小，单位bytes
+	Size int64 `protobuf:"varint,4,opt,name=Size" json:"Size" xml:"Size,omitempty"`
+	// 文件内容
+	Content []byte `protobuf:"bytes,5,opt,name=Content,proto3" json:"Content" xml:"Content,omitempty"`
+}
+
+func (m *FormFile) Reset()                    { *m = FormFile{} }
+func (m *FormFile) String() string            { return proto.CompactTextString(m) }
+func (*FormFile) ProtoMessage()               {}
+func (*FormFile) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *FormFile) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *FormFile) GetFilename() string {
+	if m != nil {
+		return m.Filename
+	}
+	return ""
+}
+
+func (m *FormFile) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *FormFile) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *FormFile) GetContent() []byte {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+// Values 同一个key的多个值
+type Values struct {
+	Values []string `protobuf:"bytes,1,rep,name=Values" json:"Values" xml:"Values,omitempty"`
+}
+
+func (m *Values) Reset()                    { *m = Values{} }
+func (m *Values) String() string            { return proto.CompactTextString(m) }
+func (*Values) ProtoMessage()               {}
+func (*Values) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Values) GetValues() []string {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Proto)(nil), "protocol.Proto")
+	proto.RegisterType((*FormFile)(nil), "protocol.FormFile")
+	proto.RegisterType((*Values)(nil), "protocol.Values")
 	proto.RegisterEnum("protocol.RestfulMethod", RestfulMethod_name, RestfulMethod_value)
 	proto.RegisterEnum("protocol.RestfulFormat", RestfulFormat_name, RestfulFormat_value)
 }
@@ -212,34 +332,47 @@ func init() {
 func init() { proto.RegisterFile("iceberg.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 460 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x91, 0x4f, 0x6f, 0xd3, 0x40,
-	0x10, 0xc5, 0xf1, 0x9f, 0x38, 0xce, 0xe4, 0x8f, 0x56, 0x23, 0x24, 0x96, 0x00, 0x95, 0xc5, 0xc9,
-	0xaa, 0x84, 0x91, 0xda, 0x03, 0x14, 0x4e, 0xb1, 0xe2, 0xb4, 0x45, 0x71, 0x6c, 0x36, 0xb6, 0x80,
-	0xa3, 0x1b, 0x2f, 0x25, 0x22, 0xc5, 0x61, 0xeb, 0x54, 0x0a, 0xdf, 0x9a, 0x6f, 0x80, 0x76, 0xbd,
-	0x4d, 0xd3, 0x4a, 0x1c, 0x7a, 0xca, 0xcc, 0xbc, 0xf7, 0x7b, 0xab, 0xf8, 0x41, 0x7f, 0xb9, 0xe0,
-	0x17, 0x5c, 0x5c, 0x06, 0x6b, 0x51, 0xd5, 0x15, 0xba, 0xea, 0x67, 0x51, 0xad, 0x5e, 0xff, 0xb5,
-	0xa1, 0x95, 0xaa, 0xdb, 0x53, 0x68, 0x85, 0xcb, 0x3f, 0xcb, 0x92, 0x1a, 0x9e, 0xe1, 0x77, 0x58,
-	0xb3, 0xe0, 0x31, 0x38, 0x67, 0xbc, 0x28, 0xb9, 0xa0, 0xa6, 0x67, 0xf9, 0xdd, 0xa3, 0x17, 0xc1,
-	0x2d, 0x1a, 0x28, 0x2c, 0x68, 0xd4, 0xe8, 0x57, 0x2d, 0xb6, 0x4c, 0x5b, 0xf1, 0x0d, 0xd8, 0x93,
-	0x4a, 0x5c, 0x51, 0x4b, 0x21, 0xcf, 0x1f, 0x22, 0x52, 0x6b, 0x00, 0x65, 0xc3, 0x13, 0x70, 0x33,
-	0x51, 0x2c, 0x78, 0x5c, 0xac, 0xa9, 0xad, 0x90, 0x57, 0x0f, 0x91, 0x5b, 0xbd, 0xc1, 0x76, 0x76,
-	0x7c, 0x09, 0x1d, 0xc6, 0x7f, 0x6f, 0xf8, 0x75, 0x7d, 0x3e, 0xa6, 0x2d, 0xcf, 0xf0, 0x2d, 0x76,
-	0x77, 0xc0, 0x21, 0xb8, 0x73, 0x2e, 0x6e, 0x78, 0xce, 0xce, 0xa9, 0xa3, 0xfe, 0xd5, 0x6e, 0xc7,
-	0xb7, 0xe0, 0xc8, 0xc7, 0x8b, 0x9a, 0xb6, 0x3d, 0xc3, 0x1f, 0x1c, 0x3d, 0xbb, 0x7b, 0x92, 0xf1,
-	0xeb, 0xfa, 0xfb, 0x66, 0xd5, 0xc8, 0x4c, 0xdb, 0xd0, 0x83, 0xae, 0x82, 0x63, 0x5e, 0xff, 0xa8,
-	0x4a, 0xea, 0xaa, 0xbc, 0xfd, 0x93, 0x8c, 0xd4, 0x62, 0xe7, 0x3f, 0x91, 0x8d, 0xcc, 0xb4, 0x0d,
-	0x0f, 0x00, 0x18, 0xbf, 0xaa, 0x6a, 0x3e, 0x2a, 0x4b, 0x41, 0x41, 0x25, 0xee, 0x5d, 0x10, 0xc1,
-	0x0e, 0xab, 0x72, 0x4b, 0xbb, 0x9e, 0xe1, 0xf7, 0x98, 0x9a, 0x91, 0x80, 0x15, 0x09, 0x41, 0x7b,
-	0xea, 0x24, 0xc7, 0xe1, 0x09, 0x74, 0xf7, 0x4a, 0x90, 0x86, 0x9f, 0x7c, 0xab, 0x5b, 0x94, 0xa3,
-	0x6c, 0xf6, 0xa6, 0x58, 0x6d, 0x38, 0x35, 0x9b, 0x66, 0xd5, 0xf2, 0xc1, 0x7c, 0x6f, 0x0c, 0xdf,
-	0x41, 0x67, 0x57, 0xc6, 0xa3, 0xc0, 0x8f, 0xd0, 0xbf, 0x57, 0xc9, 0x63, 0xe0, 0xc3, 0x53, 0xe8,
-	0xdf, 0xfb, 0x1e, 0x38, 0x00, 0x88, 0xa3, 0xec, 0x2c, 0x19, 0xcf, 0xf2, 0xe9, 0x94, 0x3c, 0x41,
-	0x17, 0xec, 0x34, 0x99, 0x67, 0xc4, 0xc0, 0x36, 0x58, 0x69, 0x9e, 0x11, 0x53, 0x0e, 0xa7, 0x51,
-	0x46, 0x2c, 0x04, 0x70, 0xc6, 0xd1, 0x34, 0xca, 0x22, 0x62, 0x1f, 0xce, 0x76, 0x41, 0xba, 0xa3,
-	0x01, 0xc0, 0x24, 0x61, 0xf1, 0x28, 0xd3, 0x41, 0x6d, 0xb0, 0xbe, 0xc6, 0x53, 0x62, 0xc8, 0xc4,
-	0x4f, 0xf3, 0x64, 0x46, 0x4c, 0xec, 0x81, 0x9b, 0xb2, 0x24, 0x4b, 0xc2, 0x7c, 0x42, 0x2c, 0xb9,
-	0xb1, 0xd1, 0x97, 0xcf, 0x79, 0xc4, 0xbe, 0x11, 0x3b, 0x3c, 0x00, 0xb2, 0xac, 0x82, 0x4b, 0xb1,
-	0x5e, 0xe8, 0xe6, 0x8a, 0x55, 0xe8, 0xa6, 0x7a, 0x4a, 0x8d, 0x0b, 0x47, 0x5d, 0x8f, 0xff, 0x05,
-	0x00, 0x00, 0xff, 0xff, 0x55, 0x9f, 0xcd, 0x6c, 0x4e, 0x03, 0x00, 0x00,
+	// 662 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x53, 0xcb, 0x6e, 0xd3, 0x40,
+	0x14, 0xc5, 0x71, 0xfc, 0xc8, 0xcd, 0xa3, 0xc3, 0x08, 0x95, 0xa1, 0x40, 0x31, 0x5d, 0x20, 0xab,
+	0x12, 0x41, 0x6a, 0x17, 0x50, 0x58, 0xa0, 0xa4, 0x71, 0x48, 0x51, 0x12, 0xbb, 0x13, 0x87, 0xc7,
+	0xd2, 0x4d, 0x86, 0x62, 0x91, 0xc6, 0xc1, 0x71, 0x2a, 0xa5, 0xdf, 0xc0, 0x3f, 0xf2, 0x2b, 0x68,
+	0x1e, 0x49, 0xdc, 0x52, 0x16, 0x15, 0x2b, 0xdf, 0xc7, 0x39, 0xe7, 0x7a, 0x66, 0xce, 0x85, 0x6a,
+	0x3c, 0x62, 0x67, 0x2c, 0x3d, 0xaf, 0xcf, 0xd2, 0x24, 0x4b, 0xb0, 0x2d, 0x3e, 0xa3, 0x64, 0xb2,
+	0xf7, 0xdb, 0x02, 0x23, 0x10, 0xb5, 0x07, 0x60, 0x34, 0xe3, 0xab, 0x78, 0x4c, 0x34, 0x47, 0x73,
+	0x4b, 0x54, 0x26, 0xf8, 0x10, 0xcc, 0x0e, 0x8b, 0xc6, 0x2c, 0x25, 0x05, 0x47, 0x77, 0xcb, 0x07,
+	0x8f, 0xeb, 0x2b, 0x6a, 0x5d, 0xd0, 0xea, 0xb2, 0xeb, 0x4d, 0xb3, 0x74, 0x49, 0x15, 0x14, 0xbf,
+	0x84, 0x62, 0x3b, 0x49, 0x2f, 0x88, 0x2e, 0x28, 0x8f, 0x6e, 0x52, 0x78, 0x4f, 0x12, 0x04, 0x0c,
+	0x1f, 0x81, 0x1d, 0xa6, 0xd1, 0x88, 0xf5, 0xa2, 0x19, 0x29, 0x0a, 0xca, 0xd3, 0x9b, 0x94, 0x55,
+	0x5f, 0xd2, 0xd6, 0x70, 0xfc, 0x04, 0x4a, 0x94, 0xfd, 0x5c, 0xb0, 0x79, 0x76, 0xd2, 0x22, 0x86,
+	0xa3, 0xb9, 0x3a, 0xdd, 0x14, 0xf0, 0x0e, 0xd8, 0x03, 0x96, 0x5e, 0xb2, 0x21, 0x3d, 0x21, 0xa6,
+	0x38, 0xd5, 0x3a, 0xc7, 0xaf, 0xc0, 0xe4, 0xc3, 0xa3, 0x8c, 0x58, 0x8e, 0xe6, 0xd6, 0x0e, 0x1e,
+	0x6e, 0x46, 0x52, 0x36, 0xcf, 0xbe, 0x2d, 0x26, 0xb2, 0x4d, 0x15, 0x0c, 0x3b, 0x50, 0x16, 0xe4,
+	0x1e, 0xcb, 0xbe, 0x27, 0x63, 0x62, 0x0b, 0xbd, 0x7c, 0x89, 0x4b, 0xaa, 0x66, 0xe9, 0x1f, 0x92,
+	0xb2, 0x4d, 0x15, 0x0c, 0xef, 0x02, 0x50, 0x76, 0x91, 0x64, 0xac, 0x31, 0x1e, 0xa7, 0x04, 0x84,
+	0x62, 0xae, 0x82, 0x31, 0x14, 0x9b, 0xc9, 0x78, 0x49, 0xca, 0x8e, 0xe6, 0x56, 0xa8, 0x88, 0x31,
+	0x02, 0xdd, 0x4b, 0x53, 0x52, 0x11, 0x25, 0x1e, 0x62, 0x17, 0x8c, 0x76, 0x3c, 0x61, 0x73, 0x52,
+	0x15, 0x77, 0x87, 0x37, 0x53, 0xf9, 0x9f, 0xf3, 0x16, 0x95, 0x00, 0xbc, 0x0d, 0xe6, 0x20, 0x8b,
+	0xb2, 0xc5, 0x9c, 0xd4, 0x1c, 0xcd, 0x35, 0xa8, 0xca, 0xb0, 0x07, 0x15, 0xf9, 0x72, 0x9f, 0xa2,
+	0xc9, 0x82, 0xcd, 0xc9, 0x96, 0x10, 0x7a, 0x7e, 0xfb, 0x53, 0x4b, 0x8c, 0x7c, 0x88, 0x6b, 0x34,
+	0xfc, 0x1e, 0x80, 0x4f, 0x54, 0x22, 0x48, 0x88, 0x3c, 0xbb, 0xed, 0xf1, 0xf3, 0x12, 0x39, 0xca,
+	0xce, 0x11, 0x94, 0x73, 0x76, 0xe2, 0x47, 0xfd, 0xc1, 0x96, 0xca, 0x8f, 0x3c, 0xe4, 0x1e, 0xbd,
+	0xe4, 0x50, 0x52, 0x90, 0x1e, 0x15, 0xc9, 0xdb, 0xc2, 0x1b, 0x6d, 0xe7, 0x35, 0x94, 0xd6, 0xb6,
+	0xba, 0x13, 0xf1, 0x1d, 0x54, 0xaf, 0x99, 0xeb, 0x4e, 0xe4, 0x53, 0xb8, 0xff, 0xd7, 0xa5, 0xdc,
+	0x22, 0xf0, 0x22, 0x2f, 0x50, 0x3e, 0x40, 0x9b, 0x3b, 0x91, 0xbc, 0xbc, 0xa4, 0x0f, 0x5b, 0x37,
+	0xae, 0xe8, 0xff, 0x04, 0xf7, 0x7e, 0x69, 0x60, 0xaf, 0x8c, 0xc0, 0x8f, 0xd2, 0x8e, 0xd9, 0x64,
+	0xbd, 0xe4, 0x22, 0xe1, 0x7b, 0xc2, 0xbb, 0xd3, 0xe8, 0x62, 0x75, 0xc6, 0x75, 0xce, 0x6d, 0x7f,
+	0x9c, 0x4c, 0x33, 0x36, 0xcd, 0xc2, 0xe5, 0x8c, 0x11, 0x5d, 0xda, 0x3e, 0x57, 0xe2, 0x2e, 0x1d,
+	0xc4, 0x57, 0x8c, 0x14, 0xc5, 0xfa, 0x89, 0x18, 0x13, 0xb0, 0x14, 0x44, 0x6c, 0x65, 0x85, 0xae,
+	0xd2, 0x3d, 0x07, 0x4c, 0x65, 0x97, 0xed, 0x55, 0x44, 0x34, 0x47, 0x77, 0x4b, 0x54, 0x65, 0xfb,
+	0x31, 0x54, 0xaf, 0xad, 0x0b, 0xae, 0x01, 0xf4, 0xbc, 0xb0, 0xe3, 0xb7, 0xfa, 0xc3, 0x6e, 0x17,
+	0xdd, 0xc3, 0x36, 0x14, 0x03, 0x7f, 0x10, 0x22, 0x0d, 0x5b, 0xa0, 0x07, 0xc3, 0x10, 0x15, 0x78,
+	0xf0, 0xc1, 0x0b, 0x91, 0x8e, 0x01, 0xcc, 0x96, 0xd7, 0xf5, 0x42, 0x0f, 0x15, 0x71, 0x09, 0x8c,
+	0xa0, 0x11, 0x1e, 0x77, 0x90, 0xc1, 0x29, 0x1d, 0xaf, 0xd1, 0x42, 0x26, 0x2e, 0x83, 0xe5, 0x07,
+	0xe1, 0x89, 0xdf, 0x1f, 0x20, 0x6b, 0xbf, 0xbf, 0x1e, 0xa5, 0x96, 0xbc, 0x06, 0xd0, 0xf6, 0x69,
+	0xaf, 0x11, 0xaa, 0x51, 0x16, 0xe8, 0x5f, 0x7a, 0x5d, 0xa4, 0x71, 0x81, 0x8f, 0x03, 0xbf, 0x8f,
+	0x0a, 0xb8, 0x02, 0x76, 0x40, 0xfd, 0xd0, 0x6f, 0x0e, 0xdb, 0x48, 0xe7, 0x19, 0x6d, 0x7c, 0x3e,
+	0x1d, 0x7a, 0xf4, 0x2b, 0x2a, 0x36, 0x77, 0x01, 0xc5, 0x49, 0xfd, 0x3c, 0x9d, 0x8d, 0xd4, 0x8b,
+	0x44, 0x93, 0xa6, 0x1d, 0xa8, 0x28, 0xd0, 0xce, 0x4c, 0x51, 0x3d, 0xfc, 0x33, 0x00, 0xaa, 0xc3,
+	0x1b, 0x3c, 0x8f, 0x05, 0x00, 0x00,
 }
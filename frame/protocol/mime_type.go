@@ -71,4 +71,13 @@ const (
 	HeaderXFrameOptions           = "X-Frame-Options"
 	HeaderContentSecurityPolicy   = "Content-Security-Policy"
 	HeaderXCSRFToken              = "X-CSRF-Token"
+
+	// Iceberg
+	HeaderXIcebergPrefix        = "X-Iceberg-"                 // 框架内部使用的Header前缀
+	HeaderXIcebergDebug         = "X-Iceberg-Debug"            // 值为1或true时该请求经过的所有服务都输出DEBUG日志
+	HeaderXIcebergTimeout       = "X-Iceberg-Timeout"          // 请求剩余的超时时间，单位毫秒
+	HeaderXIcebergBaggagePrefix = "X-Iceberg-Baggage-"         // 沿调用链传递的baggage
+	HeaderXIcebergCaller        = "X-Iceberg-Caller"           // 调用方服务名称
+	HeaderXIcebergCallerTime    = "X-Iceberg-Caller-Time"      // 调用方签名时间，unix秒
+	HeaderXIcebergCallerSign    = "X-Iceberg-Caller-Signature" // 调用方签名
 )
@@ -6,6 +6,8 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 
 	"github.com/golang/protobuf/proto"
 )
@@ -28,8 +30,16 @@ const (
 
 // ErrInfo 服务之间通用的错误信息结构
 type ErrInfo struct {
-	ErrCode int    `json:"errcode"`
-	ErrInfo string `json:"errmsg"`
+	ErrCode int          `json:"errcode"`
+	ErrInfo string       `json:"errmsg"`
+	Fields  []FieldError `json:"fields,omitempty"` // 校验失败的字段
+}
+
+// FieldError 字段错误
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Msg   string `json:"msg"`
 }
 
 // Proto 服务层内部接口的协议采用json编码。
@@ -106,22 +116,127 @@ func (pro *Proto) Shadow() Proto {
 	return shadow
 }
 
-// Context 返回一个包裹Bizid和Header信息的Context
+type contextKey struct{}
+
+// requestInfo Proto.Context中保存的请求信息
+type requestInfo struct {
+	bizid  string
+	header map[string]string
+}
+
+// Context 返回一个包裹Bizid和Header信息的Context，使用BizidFromContext，HeaderFromContext获取
 func (pro *Proto) Context() context.Context {
-	ctx := context.TODO()
-	ctx = context.WithValue(ctx, "bizid", pro.GetBizid())
-	for k, v := range pro.Header {
-		ctx = context.WithValue(ctx, k, v)
+	return context.WithValue(context.TODO(), contextKey{},
+		&requestInfo{bizid: pro.GetBizid(), header: pro.GetHeader()})
+}
+
+// BizidFromContext 获取Proto.Context中的Bizid
+func BizidFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		return info.bizid
 	}
-	return ctx
+	return ""
 }
 
-// SetHeader set http header
+// HeaderFromContext 获取Proto.Context中的Header
+func HeaderFromContext(ctx context.Context, key string) string {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		return info.header[key]
+	}
+	return ""
+}
+
+// SetHeader set http header，替换该Header的所有值
 func (pro *Proto) SetHeader(key, val string) {
 	if pro.Header == nil {
 		pro.Header = make(map[string]string)
 	}
 	pro.Header[key] = val
+	delete(pro.HeaderValues, http.CanonicalHeaderKey(key))
+}
+
+// AddHeader 添加Header的值，如Set-Cookie
+func (pro *Proto) AddHeader(key, val string) {
+	pro.Header, pro.HeaderValues = addValue(pro.Header, pro.HeaderValues, http.CanonicalHeaderKey(key), val)
+}
+
+// DelHeader 删除Header的所有值
+func (pro *Proto) DelHeader(key string) {
+	ck := http.CanonicalHeaderKey(key)
+	delete(pro.Header, key)
+	delete(pro.Header, ck)
+	delete(pro.HeaderValues, ck)
+}
+
+// SetHTTPHeader 设置所有Header，替换同名Header的值
+func (pro *Proto) SetHTTPHeader(h http.Header) {
+	for k, vs := range h {
+		pro.Header, pro.HeaderValues = setValues(pro.Header, pro.HeaderValues, http.CanonicalHeaderKey(k), vs)
+	}
+}
+
+// HTTPHeader 合并Header和HeaderValues，返回所有Header的所有值
+func (pro *Proto) HTTPHeader() http.Header {
+	h := make(http.Header, len(pro.GetHeader()))
+	for k, v := range pro.GetHeader() {
+		h.Set(k, v)
+	}
+	for k, vs := range pro.GetHeaderValues() {
+		h[http.CanonicalHeaderKey(k)] = append([]string(nil), vs.GetValues()...)
+	}
+	return h
+}
+
+// SetURLValues 设置表单参数，替换同名参数的值
+func (pro *Proto) SetURLValues(form url.Values) {
+	for k, vs := range form {
+		pro.Form, pro.FormValues = setValues(pro.Form, pro.FormValues, k, vs)
+	}
+}
+
+// URLValues 合并Form和FormValues，返回所有参数的所有值
+func (pro *Proto) URLValues() url.Values {
+	form := make(url.Values, len(pro.GetForm()))
+	for k, v := range pro.GetForm() {
+		form.Set(k, v)
+	}
+	for k, vs := range pro.GetFormValues() {
+		form[k] = append([]string(nil), vs.GetValues()...)
+	}
+	return form
+}
+
+// setValues 单值map中保存第一个值(兼容只读取单值的旧版本)，有多个值时多值map中保存所有值
+func setValues(single map[string]string, multi map[string]*Values, key string, vs []string) (map[string]string, map[string]*Values) {
+	if len(vs) == 0 {
+		return single, multi
+	}
+	if single == nil {
+		single = make(map[string]string)
+	}
+	single[key] = vs[0]
+	if len(vs) == 1 {
+		delete(multi, key)
+		return single, multi
+	}
+	if multi == nil {
+		multi = make(map[string]*Values)
+	}
+	multi[key] = &Values{Values: append([]string(nil), vs...)}
+	return single, multi
+}
+
+// addValue 添加一个值
+func addValue(single map[string]string, multi map[string]*Values, key, val string) (map[string]string, map[string]*Values) {
+	first, ok := single[key]
+	if !ok {
+		return setValues(single, multi, key, []string{val})
+	}
+	if vs, ok := multi[key]; ok {
+		vs.Values = append(vs.Values, val)
+		return single, multi
+	}
+	return setValues(single, multi, key, []string{first, val})
 }
 
 // ForeachKey 实现opentracing TextMapReader接口，用于opentacing Extract
@@ -134,6 +249,14 @@ func (pro *Proto) ForeachKey(handler func(key, val string) error) error {
 	return nil
 }
 
+// Set 实现opentracing TextMapWriter接口，用于opentacing Inject
+func (pro *Proto) Set(key, val string) {
+	if pro.TraceMap == nil {
+		pro.TraceMap = make(map[string]string)
+	}
+	pro.TraceMap[key] = val
+}
+
 // AsString 将结构体序列化后的结果，转成可读的字符串
 // 其实就是剥离包头表示长度的字节，因为序列化是json操作。所以剥离包头的长度后就是可读的内容了
 func (pro *Proto) AsString() string {
@@ -145,6 +268,9 @@ func (pro *Proto) AsString() string {
 func (pro *Proto) FillErrInfo(code int, err error) {
 	pro.Body = make([]byte, 0)
 	errInfo := ErrInfo{ErrCode: code, ErrInfo: err.Error()}
+	if fe, ok := err.(interface{ FieldErrors() []FieldError }); ok {
+		errInfo.Fields = fe.FieldErrors()
+	}
 	pro.Err, _ = json.Marshal(&errInfo)
 }
 
@@ -0,0 +1,118 @@
+package frame
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	log "github.com/kwins/iceberg/frame/icelog"
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// panic恢复
+// 服务处理请求和gateway转发请求时发生的panic不会导致进程退出，请求返回500和bizid，
+// 同时输出堆栈，计入方法的panic统计，并调用SetCrashHook设置的上报方法
+
+// CrashReport 崩溃信息
+type CrashReport struct {
+	Service   string
+	Method    string
+	URI       string
+	Bizid     string
+	RequestID int64
+	Panic     interface{}
+	Stack     []byte
+	Time      time.Time
+}
+
+// CrashHook 崩溃上报，如上报到sentry
+type CrashHook func(r *CrashReport)
+
+var (
+	crashHook   CrashHook
+	crashLocker sync.RWMutex
+)
+
+// SetCrashHook 设置崩溃上报方法
+func SetCrashHook(hook CrashHook) {
+	crashLocker.Lock()
+	crashHook = hook
+	crashLocker.Unlock()
+}
+
+// PanicError 处理请求时发生panic，返回给调用方的错误只包含bizid，不包含panic的详细信息
+type PanicError struct {
+	Bizid string
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("internal error,bizid=%s", e.Bizid)
+}
+
+// Status 响应状态码
+func (e *PanicError) Status() int {
+	return http.StatusInternalServerError
+}
+
+// RecoverPanic 处理recover得到的panic，在defer中调用
+// req 发生panic的请求，可以为空
+func RecoverPanic(rec interface{}, req *protocol.Proto) *PanicError {
+	buf := make([]byte, 64<<10)
+	buf = buf[:runtime.Stack(buf, false)]
+	pe := &PanicError{Bizid: req.GetBizid(), Value: rec, Stack: buf}
+
+	method := req.GetServeMethod()
+	if method == "" {
+		method = req.GetServeURI()
+	}
+	discover := Instance()
+	discover.methodStat(method).addPanic()
+	log.Errorw("panic",
+		log.String("bizid", req.GetBizid()),
+		log.Int64("request_id", req.GetRequestID()),
+		log.String("uri", req.GetServeURI()),
+		log.String("method", req.GetServeMethod()),
+		log.String("panic", fmt.Sprint(rec)),
+		log.String("stack", string(buf)))
+
+	crashLocker.RLock()
+	hook := crashHook
+	crashLocker.RUnlock()
+	if hook != nil {
+		reportCrash(hook, &CrashReport{
+			Service:   discover.name,
+			Method:    req.GetServeMethod(),
+			URI:       req.GetServeURI(),
+			Bizid:     req.GetBizid(),
+			RequestID: req.GetRequestID(),
+			Panic:     rec,
+			Stack:     buf,
+			Time:      time.Now(),
+		})
+	}
+	return pe
+}
+
+// reportCrash 上报方法自身panic时不影响请求
+func reportCrash(hook CrashHook, r *CrashReport) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Errorf("crash hook panic:%v", rec)
+		}
+	}()
+	hook(r)
+}
+
+// serve 执行方法的调用链，panic转为PanicError
+func (discover *Discover) serve(c Context, md *MethodDesc) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = RecoverPanic(rec, c.Request())
+		}
+	}()
+	return discover.handler(md)(c)
+}
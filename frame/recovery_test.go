@@ -0,0 +1,129 @@
+package frame
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kwins/iceberg/frame/protocol"
+	"github.com/opentracing/opentracing-go"
+)
+
+func TestServeRecover(t *testing.T) {
+	var report *CrashReport
+	SetCrashHook(func(r *CrashReport) {
+		report = r
+		panic("hook panic")
+	})
+	defer SetCrashHook(nil)
+
+	discover := &Discover{methodInterceptors: make(map[string][]Interceptor)}
+	md := &MethodDesc{MethodName: "crash", Handler: func(srv interface{}, c Context) error {
+		var m map[string]int
+		m["a"] = 1
+		return nil
+	}}
+	c := NewContext()
+	c.Reset(&protocol.Proto{Bizid: "b1", ServeMethod: "crash"}, &protocol.Proto{})
+	err := discover.serve(c, md)
+	if err == nil || errStatus(err) != 500 {
+		t.Fatalf("panic should become 500 error,%v", err)
+	}
+	c.Response().FillErrInfo(errStatus(err), err)
+	var info protocol.ErrInfo
+	json.Unmarshal(c.Response().GetErr(), &info)
+	if info.ErrCode != 500 || !strings.Contains(info.ErrInfo, "b1") || strings.Contains(info.ErrInfo, "nil map") {
+		t.Errorf("error response got %+v", info)
+	}
+	if report == nil || report.Bizid != "b1" || report.Method != "crash" ||
+		!strings.Contains(string(report.Stack), "recovery_test.go") {
+		t.Errorf("crash report got %+v", report)
+	}
+	if MethodStats()["crash"].Panic != 1 {
+		t.Errorf("panic should be counted")
+	}
+}
+
+// panicTracer Extract时panic，模拟处理方法外的panic
+type panicTracer struct {
+	opentracing.NoopTracer
+}
+
+func (panicTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	panic("extract panic")
+}
+
+func TestProcessInComingRecover(t *testing.T) {
+	opentracing.SetGlobalTracer(panicTracer{})
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	ca := NewPassiveConnActor(server)
+	defer ca.Close()
+
+	req := protocol.Proto{Bizid: "b2", RequestID: 7, ServeURI: "/services/v1/hello", ServeMethod: "sayhi"}
+	b, err := req.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go client.Write(b)
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	pack, err := RecvPack(client)
+	if err != nil {
+		t.Fatalf("no response after panic,%v", err)
+	}
+	var resp protocol.Proto
+	if err := resp.UnSerialize(pack); err != nil {
+		t.Fatal(err)
+	}
+	var info protocol.ErrInfo
+	json.Unmarshal(resp.GetErr(), &info)
+	if resp.GetRequestID() != 7 || info.ErrCode != 500 || !strings.Contains(info.ErrInfo, "b2") {
+		t.Errorf("panic response got %d %+v", resp.GetRequestID(), info)
+	}
+}
+
+func TestProcessInComingStatusThenError(t *testing.T) {
+	discover := Instance()
+	discover.mdLocker.Lock()
+	discover.md["statuserr"] = &MethodDesc{MethodName: "statuserr", Handler: func(srv interface{}, c Context) error {
+		c.Redirect(http.StatusFound, "/login")
+		c.SetCookie(&http.Cookie{Name: "sid", Value: "1"})
+		return ErrMethodNotFound
+	}}
+	discover.mdLocker.Unlock()
+	defer func() {
+		discover.mdLocker.Lock()
+		delete(discover.md, "statuserr")
+		discover.mdLocker.Unlock()
+	}()
+
+	server, client := net.Pipe()
+	defer client.Close()
+	ca := NewPassiveConnActor(server)
+	defer ca.Close()
+
+	req := protocol.Proto{Bizid: "b3", RequestID: 8, ServeURI: "/services/v1/hello", ServeMethod: "statuserr"}
+	b, err := req.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go client.Write(b)
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	pack, err := RecvPack(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp protocol.Proto
+	if err := resp.UnSerialize(pack); err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != 0 || len(resp.HTTPHeader()) != 0 || len(resp.GetErr()) == 0 {
+		t.Errorf("error response should reset status and header,%d %v %s",
+			resp.GetStatus(), resp.HTTPHeader(), resp.GetErr())
+	}
+}
@@ -0,0 +1,274 @@
+package frame
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// RoutePolicy 服务的流量路由策略，用于灰度发布
+// 保存在etcd中 /services/v1/<svc>/provider/route，值为JSON
+// 规则按顺序匹配，第一个匹配的规则决定流量去向，都不匹配时走默认拓扑
+// Match和HashBy需要请求的Header,Cookie和表单，只在带请求的路由(Route，DeliverTo)中生效;
+// Get和Dispatch只有URI，跳过有Match的规则，其他规则按权重随机分流
+//
+// 例如将带 X-Canary:true 的请求全部导入tag为canary的实例，其余请求
+// 按用户ID哈希，10%导入v2版本：
+//
+//	{
+//	  "rules":[
+//	    {"match":{"headers":{"X-Canary":"true"}},"destinations":[{"tag":"canary","weight":100}]},
+//	    {"hash_by":"header:X-User-Id","destinations":[{"weight":90},{"version":"v2","weight":10}]}
+//	  ]
+//	}
+type RoutePolicy struct {
+	Rules []RouteRule `json:"rules"`
+}
+
+// RouteRule 一条路由规则
+// Match 为空表示匹配所有请求;
+// HashBy 按请求中的某个值哈希分流，保证同一用户落在同一分组，
+// 格式为 header:<name>，cookie:<name>，form:<name>，为空时按权重随机;
+// Destinations 分流目标及权重;
+type RouteRule struct {
+	Match        RouteMatch         `json:"match"`
+	HashBy       string             `json:"hash_by"`
+	Destinations []RouteDestination `json:"destinations"`
+}
+
+// RouteMatch 请求匹配条件，所有条件都满足才算匹配
+// 值为 * 表示只要存在即可
+type RouteMatch struct {
+	Headers map[string]string `json:"headers"`
+	Cookies map[string]string `json:"cookies"`
+}
+
+// RouteDestination 分流目标
+// Version 目标服务版本，为空表示当前版本，如 v2 表示转发到 /services/v2/<svc>;
+// Tag 只选择带有该标签的实例，为空表示不限;
+// Weight 分流权重;
+type RouteDestination struct {
+	Version string `json:"version"`
+	Tag     string `json:"tag"`
+	Weight  int    `json:"weight"`
+}
+
+var routeRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var routeRandLocker sync.Mutex
+
+// ParseRoutePolicy 解析路由策略
+func ParseRoutePolicy(value []byte) (*RoutePolicy, error) {
+	var policy RoutePolicy
+	if err := json.Unmarshal(value, &policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Destination 根据请求找出流量去向，没有匹配的规则时返回nil
+func (policy *RoutePolicy) Destination(task *protocol.Proto) *RouteDestination {
+	header := make(http.Header)
+	if task != nil {
+		for k, v := range task.GetHeader() {
+			header.Set(k, v)
+		}
+	}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if !rule.Match.matched(header) {
+			continue
+		}
+		return rule.pick(header, task)
+	}
+	return nil
+}
+
+func (match *RouteMatch) matched(header http.Header) bool {
+	for k, want := range match.Headers {
+		if !matchValue(header.Get(k), want) {
+			return false
+		}
+	}
+	if len(match.Cookies) == 0 {
+		return true
+	}
+	r := http.Request{Header: header}
+	for k, want := range match.Cookies {
+		var got string
+		if cookie, err := r.Cookie(k); err == nil {
+			got = cookie.Value
+		}
+		if !matchValue(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchValue(got, want string) bool {
+	if want == "*" {
+		return got != ""
+	}
+	return got == want
+}
+
+func (rule *RouteRule) pick(header http.Header, task *protocol.Proto) *RouteDestination {
+	var total int
+	for _, d := range rule.Destinations {
+		if d.Weight > 0 {
+			total += d.Weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var bucket int
+	if key := rule.hashKey(header, task); key != "" {
+		bucket = int(_hash([]byte(key)) % uint32(total))
+	} else {
+		routeRandLocker.Lock()
+		bucket = routeRand.Intn(total)
+		routeRandLocker.Unlock()
+	}
+
+	for i := range rule.Destinations {
+		d := &rule.Destinations[i]
+		if d.Weight <= 0 {
+			continue
+		}
+		if bucket < d.Weight {
+			return d
+		}
+		bucket -= d.Weight
+	}
+	return nil
+}
+
+func (rule *RouteRule) hashKey(header http.Header, task *protocol.Proto) string {
+	i := strings.IndexByte(rule.HashBy, ':')
+	if i == -1 {
+		return ""
+	}
+	name := rule.HashBy[i+1:]
+	switch strings.ToLower(rule.HashBy[:i]) {
+	case "header":
+		return header.Get(name)
+	case "cookie":
+		r := http.Request{Header: header}
+		if cookie, err := r.Cookie(name); err == nil {
+			return cookie.Value
+		}
+	case "form":
+		return task.GetForm()[name]
+	}
+	return ""
+}
+
+// withVersion 将服务URI中的版本替换为指定版本
+// /services/v1/hello => /services/v2/hello
+func withVersion(URI, version string) string {
+	if version == "" {
+		return URI
+	}
+	segment := strings.Split(URI, "/")
+	if len(segment) < 3 {
+		return URI
+	}
+	segment[2] = version
+	return strings.Join(segment, "/")
+}
+
+// setRoute 更新服务的路由策略，值为空时删除
+func (discover *Discover) setRoute(URI, value string) {
+	if value == "" {
+		discover.rmRoute(URI)
+		return
+	}
+	policy, err := ParseRoutePolicy([]byte(value))
+	if err != nil {
+		routeLog.Errorf("iceberg:bad route policy of %s,detail=%s", URI, err.Error())
+		return
+	}
+	discover.routeLocker.Lock()
+	discover.routes[URI] = policy
+	discover.routeLocker.Unlock()
+	routeLog.Infof("iceberg:route policy of %s changed:%s", URI, value)
+}
+
+func (discover *Discover) rmRoute(URI string) {
+	discover.routeLocker.Lock()
+	delete(discover.routes, URI)
+	discover.routeLocker.Unlock()
+	routeLog.Infof("iceberg:route policy of %s removed", URI)
+}
+
+func (discover *Discover) getRoute(URI string) *RoutePolicy {
+	discover.routeLocker.RLock()
+	defer discover.routeLocker.RUnlock()
+	return discover.routes[URI]
+}
+
+// pick 按路由策略选出处理请求的实例地址
+// URI 服务在服务树中注册的位置; task 请求，为空时只按权重分流;
+// sel 调用方指定的实例选择条件，可以为空
+// 路由目标分组中没有可用实例时回落到默认拓扑
+func (discover *Discover) pick(URI string, task *protocol.Proto, sel *MetaSelector) (string, string) {
+	accept := discover.selector(sel)
+	if policy := discover.getRoute(URI); policy != nil {
+		if d := policy.Destination(task); d != nil {
+			target := withVersion(URI, d.Version)
+			routeAccept := accept
+			if d.Tag != "" {
+				tagSel := &MetaSelector{Tags: []string{d.Tag}}
+				routeAccept = both(accept, discover.selector(tagSel))
+			}
+			if addr := discover.leastload(target, routeAccept); addr != "" {
+				return addr, target
+			}
+			routeLog.Warnf("iceberg:route %s to version=%s tag=%s found no instance, fallback",
+				URI, d.Version, d.Tag)
+		}
+	}
+	return discover.leastload(URI, accept), URI
+}
+
+// both 组合两个过滤条件，nil表示不过滤
+func both(a, b func(string) bool) func(string) bool {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return func(addr string) bool { return a(addr) && b(addr) }
+}
+
+func (discover *Discover) leastload(URI string, accept func(string) bool) string {
+	discover.topoLocker.RLock()
+	node, ok := discover.topology[URI]
+	discover.topoLocker.RUnlock()
+	if !ok {
+		return ""
+	}
+	if discover.outlier != nil {
+		// 优先选择没有被摘除的实例，全部被摘除时忽略摘除状态
+		if addr := node.LeastloadWeight(discover.localize(node, both(accept, discover.available)), discover.weight); addr != "" {
+			return addr
+		}
+	}
+	return node.LeastloadWeight(discover.localize(node, accept), discover.weight)
+}
+
+// weight 实例元数据中的权重，没有注册元数据时为默认权重
+func (discover *Discover) weight(svrAddr string) int {
+	if meta, ok := discover.Meta(svrAddr); ok && meta.Weight > 0 {
+		return meta.Weight
+	}
+	return defaultWeight
+}
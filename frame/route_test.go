@@ -0,0 +1,51 @@
+package frame
+
+import (
+	"testing"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+func TestRoutePolicy(t *testing.T) {
+	policy, err := ParseRoutePolicy([]byte(`{"rules":[
+		{"match":{"headers":{"X-Canary":"true"}},"destinations":[{"tag":"canary","weight":100}]},
+		{"match":{"cookies":{"beta":"*"}},"destinations":[{"version":"v2","weight":1}]},
+		{"hash_by":"header:X-User-Id","destinations":[{"weight":90},{"version":"v2","weight":10}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	task := protocol.Proto{Header: map[string]string{"X-Canary": "true"}}
+	if d := policy.Destination(&task); d == nil || d.Tag != "canary" {
+		t.Errorf("header match fail, got %v", d)
+	}
+
+	task = protocol.Proto{Header: map[string]string{"Cookie": "beta=1; uid=2"}}
+	if d := policy.Destination(&task); d == nil || d.Version != "v2" {
+		t.Errorf("cookie match fail, got %v", d)
+	}
+
+	// 同一用户必须落在同一分组
+	task = protocol.Proto{Header: map[string]string{"X-User-Id": "10086"}}
+	first := policy.Destination(&task)
+	for i := 0; i < 100; i++ {
+		if d := policy.Destination(&task); d != first {
+			t.Fatalf("hash by user fail, got %v want %v", d, first)
+		}
+	}
+
+	// 没有请求时(Get，Dispatch)跳过有Match的规则
+	if d := policy.Destination(nil); d == nil || d.Tag == "canary" {
+		t.Errorf("nil task should skip match rules, got %v", d)
+	}
+}
+
+func TestWithVersion(t *testing.T) {
+	if uri := withVersion("/services/v1/hello", "v2"); uri != "/services/v2/hello" {
+		t.Errorf("withVersion got %s", uri)
+	}
+	if uri := withVersion("/services/v1/hello", ""); uri != "/services/v1/hello" {
+		t.Errorf("withVersion got %s", uri)
+	}
+}
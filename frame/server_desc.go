@@ -2,7 +2,7 @@ package frame
 
 import (
 	"github.com/kwins/iceberg/frame/protocol"
-	"github.com/kwins/iceberg/frame/util"
+	"github.com/kwins/iceberg/frame/validate"
 
 	objectid "github.com/nobugtodebug/go-objectid"
 	"github.com/opentracing/opentracing-go"
@@ -18,8 +18,14 @@ type MethodDesc struct {
 	// 方法名称
 	MethodName string
 
+	// 方法接受的HTTP方法，为空时不限制，由Gateway检查
+	HTTPMethods []string
+
 	// 调起方法的句柄
 	Handler methodHandler
+
+	// 方法的拦截器
+	Interceptors []Interceptor
 }
 
 // ServiceDesc 服务描述
@@ -32,6 +38,8 @@ type ServiceDesc struct {
 	Methods     []MethodDesc
 	Metadata    interface{}
 	ServiceURI  []string
+	// 服务所有方法的拦截器，在Use注册的全局拦截器之后执行
+	Interceptors []Interceptor
 }
 
 // ReadyTask 准备请求的任务
@@ -54,13 +62,14 @@ func ReadyTask(fc Context,
 	task.ServeMethod = srvMethod
 	task.Format = c.format
 	task.Header = make(map[string]string)
-	for k := range c.header {
-		task.Header[k] = c.header.Get(k)
+	// 调试标记沿调用链传递
+	if debugRequest(fc.Request()) {
+		task.Header[protocol.HeaderXIcebergDebug] = "1"
 	}
+	injectMetadata(fc, &task)
+	task.SetHTTPHeader(c.header)
 	task.Form = make(map[string]string)
-	for k, v := range c.form {
-		task.Form[k] = v
-	}
+	task.SetURLValues(c.form)
 	// 默认序列化方式为JSON
 	if task.Format == protocol.RestfulFormat_FORMATNULL {
 		task.Format = protocol.RestfulFormat_JSON
@@ -68,22 +77,38 @@ func ReadyTask(fc Context,
 	task.RequestID = GetInnerID()
 	task.ServeURI = "/services/" + srvVersion + "/" + srvName
 	task.Method = protocol.RestfulMethod_POST
+	// 发送前校验请求参数
+	if err := validate.Check(in); err != nil {
+		return nil, err
+	}
 	b, err := protocol.Pack(task.Format, in)
 	if err != nil {
 		return nil, err
 	}
-	// inject(fc, &task)
+	if c.ctx != nil {
+		if err := injectContext(c.ctx, &task); err != nil {
+			return nil, err
+		}
+	}
+	if err := injectContext(fc.Ctx(), &task); err != nil {
+		return nil, err
+	}
+	inject(fc, &task)
 	task.Body = b
 	return &task, nil
 }
 
+// inject 将Context中的Span注入下游请求，Context中没有Span时透传上游的追踪信息
 func inject(c Context, r *protocol.Proto) {
-	if c.Request() != nil {
-		r.TraceMap = c.Request().GetTraceMap()
-	} else if tracer := opentracing.GlobalTracer(); tracer != nil {
-		span := tracer.StartSpan(r.GetServeMethod())
-		span.SetTag("Bizid", r.GetBizid())
-		span.SetTag("Host name", util.GetHostname())
-		tracer.Inject(span.Context(), opentracing.TextMap, r)
+	if span := opentracing.SpanFromContext(c.Ctx()); span != nil {
+		if err := opentracing.GlobalTracer().Inject(span.Context(), opentracing.TextMap, r); err == nil {
+			return
+		}
+	}
+	if c.Request() == nil {
+		return
+	}
+	for k, v := range c.Request().GetTraceMap() {
+		r.Set(k, v)
 	}
 }
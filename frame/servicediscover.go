@@ -2,6 +2,7 @@ package frame
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -64,8 +65,43 @@ type Discover struct {
 
 	// self uri that register to etcd
 	// you can register multi uri
-	selfURI []string
-	name    string
+	selfURI  []string
+	name     string
+	version  string
+	selfMeta *InstanceMeta
+
+	// 灰度路由策略; key是服务的URI
+	routes      map[string]*RoutePolicy
+	routeLocker sync.RWMutex
+
+	// 实例元数据; key是实例地址
+	metas      map[string]*InstanceMeta
+	metaLocker sync.RWMutex
+
+	// 就近路由配置
+	locality config.LocalityCfg
+
+	// 异常实例检测，未开启时为nil
+	outlier *OutlierDetector
+	access  *AccessLog
+
+	startLevel string // 启动时的日志级别
+
+	traceShutdown func(context.Context) error // 链路追踪后端的退出清理，未开启时为nil
+
+	// 允许透传的元数据Header
+	forward       []string
+	forwardLocker sync.RWMutex
+
+	// 服务间调用的访问控制; policies的key为方法名称，*表示服务的所有方法
+	acl       config.ACLCfg
+	policies  map[string]*ACLPolicy
+	aclLocker sync.RWMutex
+
+	// 签名时间偏差内已校验过的签名，拒绝重放;value为过期时间，unix秒
+	signs      map[string]int64
+	signPruned int64
+	signLocker sync.Mutex
 
 	// your server
 	service interface{} // 提供服务
@@ -74,6 +110,15 @@ type Discover struct {
 	prepare []Middleware
 	after   []Middleware
 
+	// 拦截器
+	icLocker            sync.RWMutex
+	interceptors        []Interceptor
+	serviceInterceptors []Interceptor
+	methodInterceptors  map[string][]Interceptor
+	clientInterceptors  []ClientInterceptor
+
+	stats methodStats // 方法调用统计
+
 	// server describe
 	mdLocker sync.RWMutex // method
 	md       map[string]*MethodDesc
@@ -102,6 +147,9 @@ func Instance() *Discover {
 		instance.ctx, instance.cancel = context.WithCancel(context.TODO())
 		instance.topology = make(map[string]*ConsistentHash)
 		instance.connholder = make(map[string]*ConnActor)
+		instance.routes = make(map[string]*RoutePolicy)
+		instance.metas = make(map[string]*InstanceMeta)
+		instance.methodInterceptors = make(map[string][]Interceptor)
 	})
 	return instance
 }
@@ -111,7 +159,7 @@ func RegisterAndServe(sd *ServiceDesc, ss interface{}, cfg *config.BaseCfg) {
 	ht := reflect.TypeOf(sd.HandlerType).Elem()
 	st := reflect.TypeOf(ss)
 	if !st.Implements(ht) {
-		log.Fatalf("iceberg: RegisterAndServe found the handler of type %v that does not satisfy %v", st, ht)
+		discoverLog.Fatalf("iceberg: RegisterAndServe found the handler of type %v that does not satisfy %v", st, ht)
 		return
 	}
 
@@ -132,6 +180,10 @@ func RegisterAndServe(sd *ServiceDesc, ss interface{}, cfg *config.BaseCfg) {
 
 	// 注册本服务信息
 	s.service = ss
+	s.version = sd.Version
+	s.icLocker.Lock()
+	s.serviceInterceptors = sd.Interceptors
+	s.icLocker.Unlock()
 	for i := range sd.Methods {
 		d := &sd.Methods[i]
 		s.mdLocker.Lock()
@@ -154,7 +206,7 @@ func RegisterAndServe(sd *ServiceDesc, ss interface{}, cfg *config.BaseCfg) {
 	for {
 		c, err := listener.Accept()
 		if err != nil {
-			log.Error("iceberg:", err.Error())
+			discoverLog.Error("iceberg:", err.Error())
 			continue
 		}
 		ca := ConnActor{c: c, reconn: false}
@@ -186,31 +238,80 @@ func MeTables() map[string]Medesc {
 }
 
 // DeliverTo deliver request to anthor serve
-func DeliverTo(task *protocol.Proto) (*protocol.Proto, error) {
-	conn, err := Instance().Get(task.GetServeURI())
+// opts 中的实例选择条件用于挑选处理请求的实例，请求经过UseClient注册的客户端拦截器
+func DeliverTo(task *protocol.Proto, opts ...CallOption) (*protocol.Proto, error) {
+	discover := Instance()
+	discover.icLocker.RLock()
+	ics := discover.clientInterceptors
+	discover.icLocker.RUnlock()
+	if len(ics) == 0 {
+		return deliver(task, opts...)
+	}
+	return chainClientInterceptors(ics, deliver)(task, opts...)
+}
+
+func deliver(task *protocol.Proto, opts ...CallOption) (*protocol.Proto, error) {
+	c := defaultCallInfo()
+	for _, o := range opts {
+		if err := o.before(c); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := Instance().Route(task, c.selector)
 	if err != nil {
-		log.Error(err.Error())
+		discoverLog.Error(err.Error())
 		return nil, err
 	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := injectContext(ctx, task); err != nil {
+		return nil, err
+	}
+	Instance().signCaller(task)
+	if d := requestTimeout(task); d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	span := startClientSpan(opentracing.GlobalTracer(), task)
+	span.SetTag("peer.address", conn.RemoteAddr())
 	var b []byte
 	if b, err = task.Serialize(); err != nil {
+		FinishSpan(span, 0, err)
 		return nil, err
 	}
-	resp, err := conn.RequestAndReponse(b, task.GetRequestID())
+	start := time.Now()
+	resp, err := conn.RequestWithContext(ctx, b, task.GetRequestID())
+	Instance().outlier.Record(task.GetServeURI(), conn.RemoteAddr(), time.Since(start), resp, err)
 	if err != nil {
+		FinishSpan(span, 0, err)
 		return nil, err
 	}
+	FinishSpan(span, respStatus(resp), nil)
+	for _, o := range opts {
+		o.after(c)
+	}
 	return resp, nil
 }
 
-// Prepare 添加prepare middleware
+// Prepare 添加prepare middleware，在处理方法前执行，返回错误时不再执行处理方法
+// Deprecated: 使用Use
 func Prepare(mw ...Middleware) {
-	Instance().prepare = append(Instance().prepare, mw...)
+	discover := Instance()
+	discover.icLocker.Lock()
+	discover.prepare = append(discover.prepare, mw...)
+	discover.icLocker.Unlock()
 }
 
-// After 添加after middleware
+// After 添加after middleware，在处理方法成功后执行
+// Deprecated: 使用Use
 func After(mw ...Middleware) {
-	Instance().after = append(Instance().after, mw...)
+	discover := Instance()
+	discover.icLocker.Lock()
+	discover.after = append(discover.after, mw...)
+	discover.icLocker.Unlock()
 }
 
 // Start 开启服务发现机制
@@ -219,6 +320,19 @@ func (discover *Discover) Start(srvName string, cfg *config.BaseCfg, selfURI []s
 	discover.name = srvName
 	discover.localListenAddr = address
 
+	initLog(srvName, &cfg.Log)
+	discover.startLevel = log.GetLevel()
+	discover.access = NewAccessLog(cfg.AccessLog)
+	discover.access.SetTrustedProxies(cfg.Metadata.TrustedProxies)
+	SetForwardMetadata(cfg.Metadata.Forward...)
+	if file := config.LocalFile(); file != "" {
+		if err := config.Default().LoadFile(file); err != nil {
+			discoverLog.Errorf("iceberg:load local config %s fail,detail=%s", file, err.Error())
+		}
+	}
+	if err := discover.SetACL(cfg.ACL); err != nil {
+		panic(err.Error())
+	}
 	if err := discover.readyEtcd(&cfg.Etcd); err != nil {
 		panic(err.Error())
 	}
@@ -229,16 +343,23 @@ func (discover *Discover) Start(srvName string, cfg *config.BaseCfg, selfURI []s
 	if discover.localListenAddr == "" {
 		discover.localListenAddr = Netip() + ":" + RandPort()
 	}
+	discover.selfMeta = newInstanceMeta(discover.name, discover.version,
+		discover.localListenAddr, &cfg.Meta)
+	discover.setLocality(cfg.Locality)
+	discover.startOutlierDetect(cfg.Outlier)
 	// 注册自己
 	if err := discover.selfRegist(); err != nil {
 		panic(err.Error())
 	}
+	if err := discover.startTrace(cfg); err != nil {
+		discoverLog.Errorf("start trace fail,%s", err.Error())
+	}
 	go discover.discover()
 	// 程序启动告警
-	log.Infof("%s start up,local listen addr:%s,serve uri:%v", discover.name, discover.localListenAddr, discover.selfURI)
+	discoverLog.Infof("%s start up,local listen addr:%s,serve uri:%v", discover.name, discover.localListenAddr, discover.selfURI)
 }
 
-// StartZipkinTrace 启动zipkin 暂不使用
+// StartZipkinTrace 启动zipkin，endPoint为空时不启动，使用opentracing默认的NoopTracer
 func (discover *Discover) StartZipkinTrace(endPoint, srvHost, srvName string) error {
 	// Create our HTTP collector.
 	if endPoint == "" {
@@ -261,20 +382,34 @@ func (discover *Discover) StartZipkinTrace(endPoint, srvHost, srvName string) er
 	}
 	// Explicitely set our tracer to be the default tracer.
 	opentracing.InitGlobalTracer(tracer)
-	log.Infof("start zipkin trace endpoint:%s,srvHost:%s,srvName:%s", endPoint, srvHost, srvName)
+	discoverLog.Infof("start zipkin trace endpoint:%s,srvHost:%s,srvName:%s", endPoint, srvHost, srvName)
 
 	return nil
 }
 
 // Get 获取URI对应的一个可用连接
+// 配置了路由策略时按权重分流，没有请求，不匹配Header和Cookie，需要时使用Route
 func (discover *Discover) Get(URI string) (*ConnActor, error) {
 	discover.topoLocker.RLock()
-	if node, ok := discover.topology[URI]; ok {
-		discover.topoLocker.RUnlock()
-		return discover.getConnActor(node.Leastload(), URI)
+	_, ok := discover.topology[URI]
+	discover.topoLocker.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s not found in topology", URI)
 	}
+	return discover.getConnActor(discover.pick(URI, nil, nil))
+}
+
+// Route 按请求的Header,Cookie和路由策略获取一个可用连接
+// sel 实例选择条件，可以为空
+func (discover *Discover) Route(task *protocol.Proto, sel *MetaSelector) (*ConnActor, error) {
+	URI := task.GetServeURI()
+	discover.topoLocker.RLock()
+	_, ok := discover.topology[URI]
 	discover.topoLocker.RUnlock()
-	return nil, fmt.Errorf("%s not found in topology", URI)
+	if !ok {
+		return nil, fmt.Errorf("%s not found in topology", URI)
+	}
+	return discover.getConnActor(discover.pick(URI, task, sel))
 }
 
 // Allowed 是否允许不认证直接访问，给Gateway使用
@@ -298,6 +433,23 @@ func (discover *Discover) Allowed(path string) bool {
 	}
 }
 
+// Methods 方法接受的HTTP方法，服务没有声明时返回nil，给Gateway使用
+// ok 为false表示方法没有注册
+func (discover *Discover) Methods(path string) (methods []string, ok bool) {
+	ps := strings.Split(strings.ToLower(path), "/")
+	if len(ps) < 5 {
+		return nil, false
+	}
+
+	mk := strings.Join(intercept(ps), "@")
+	discover.mtLocker.RLock()
+	defer discover.mtLocker.RUnlock()
+	if md := discover.mdtables[mk]; md != nil && md.MdName != "" {
+		return md.Methods, true
+	}
+	return nil, false
+}
+
 func intercept(ss []string) []string {
 	return ss[2:5]
 }
@@ -314,13 +466,13 @@ func (discover *Discover) getConnActor(remoteAddr, uri string) (*ConnActor, erro
 	var connactor *ConnActor
 
 	createConn := func() error {
-		log.Debug("try ot connect:", remoteAddr)
+		discoverLog.Debug("try ot connect:", remoteAddr)
 		c, err := net.Dial("tcp", remoteAddr)
 		if err != nil {
-			log.Error(err.Error())
+			discoverLog.Error(err.Error())
 			return err
 		}
-		log.Debugf("connect backend serve %s success[%s]", remoteAddr, uri)
+		discoverLog.Debugf("connect backend serve %s success[%s]", remoteAddr, uri)
 		connactor = NewActiveConnActor(c)
 		discover.connLocker.Lock()
 		discover.connholder[remoteAddr] = connactor
@@ -344,33 +496,33 @@ func (discover *Discover) getConnActor(remoteAddr, uri string) (*ConnActor, erro
 }
 
 // Dispatch 找出请求被分派到哪一个实例去处理
-// URI 请求的接口路径
+// URI 请求的接口路径，路由策略的处理同Get
 func (discover *Discover) Dispatch(URI string) (*ConnActor, error) {
 	// 使用贪婪匹配模式，即匹配更长的URI
 
 	var (
 		matchedLen  int
-		remoteAddr  string
 		registerURI string
 	)
 
 	discover.topoLocker.RLock()
-	defer discover.topoLocker.RUnlock()
-
-	for k, v := range discover.topology {
+	for k := range discover.topology {
 		if strings.HasPrefix(URI, k) {
 			if len(k) > matchedLen {
 				matchedLen = len(k)
 				registerURI = k
-				if remoteAddr = v.Leastload(); remoteAddr == "" {
-					return nil, errNotFoundConnect
-				}
 			}
 		}
 	}
+	discover.topoLocker.RUnlock()
+
 	if matchedLen > 0 {
+		remoteAddr, uri := discover.pick(registerURI, nil, nil)
+		if remoteAddr == "" {
+			return nil, errNotFoundConnect
+		}
 		// 找到了节点。取出/新建连接
-		return discover.getConnActor(remoteAddr, registerURI)
+		return discover.getConnActor(remoteAddr, uri)
 	}
 	return nil, errNotFoundConnect
 }
@@ -390,7 +542,7 @@ func (discover *Discover) selfRegist() error {
 		}
 
 		svrURI := uri + "/provider/name"
-		log.Debugf("set %s=%s", svrURI, discover.name)
+		discoverLog.Debugf("set %s=%s", svrURI, discover.name)
 		_, err = discover.kapi.Put(context.TODO(), svrURI, discover.name, clientv3.WithLease(resp.ID))
 		if err != nil {
 			return err
@@ -398,12 +550,24 @@ func (discover *Discover) selfRegist() error {
 
 		// 先KeepAlive 在Put临时节点
 		svrURI = uri + "/provider/instances/" + discover.localListenAddr
-		log.Debugf("set %s=%s with leaseid=%x", svrURI, discover.localListenAddr, resp.ID)
+		discoverLog.Debugf("set %s=%s with leaseid=%x", svrURI, discover.localListenAddr, resp.ID)
 		_, err = discover.kapi.Put(context.TODO(), svrURI, discover.localListenAddr, clientv3.WithLease(resp.ID))
 		if err != nil {
 			return err
 		}
 
+		// 注册实例元数据
+		meta, err := json.Marshal(discover.selfMeta)
+		if err != nil {
+			return err
+		}
+		metaURI := uri + "/provider/meta/" + discover.localListenAddr
+		discoverLog.Debugf("set %s=%s", metaURI, meta)
+		_, err = discover.kapi.Put(context.TODO(), metaURI, string(meta), clientv3.WithLease(resp.ID))
+		if err != nil {
+			return err
+		}
+
 		// 注册方法表
 		for k, v := range discover.md {
 			mdname := uri + "/" + strings.ToLower(v.MethodName) + "/provider/allowed/" + v.Allowed
@@ -411,6 +575,14 @@ func (discover *Discover) selfRegist() error {
 			if err != nil {
 				return err
 			}
+			if len(v.HTTPMethods) > 0 {
+				mdmethods := uri + "/" + strings.ToLower(v.MethodName) + "/provider/methods"
+				_, err := discover.kapi.Put(context.TODO(), mdmethods,
+					strings.ToUpper(strings.Join(v.HTTPMethods, ",")), clientv3.WithLease(resp.ID))
+				if err != nil {
+					return err
+				}
+			}
 		}
 
 		go func(uri string, leaseid clientv3.LeaseID) {
@@ -421,7 +593,7 @@ func (discover *Discover) selfRegist() error {
 				case <-t.C:
 					gResp, err := discover.kapi.Get(context.TODO(), uri)
 					if err != nil || len(gResp.Kvs) == 0 {
-						log.Fatalf("iceberg:%s svr uri %s get fail,detail=%v",
+						discoverLog.Fatalf("iceberg:%s svr uri %s get fail,detail=%v",
 							discover.name, uri, err)
 						discover.kapi.Put(context.TODO(),
 							uri, discover.localListenAddr, clientv3.WithLease(leaseid))
@@ -455,7 +627,7 @@ func (discover *Discover) readyEtcd(cfg *config.EtcdCfg) error {
 			grpc.WithInsecure(),
 		},
 
-		DialTimeout: time.Second * cfg.Timeout,
+		DialTimeout: time.Second * time.Duration(cfg.Timeout),
 	})
 	if err != nil {
 		return err
@@ -467,7 +639,7 @@ func (discover *Discover) readyEtcd(cfg *config.EtcdCfg) error {
 	}
 	for _, subNode := range resp.Kvs {
 		if len(subNode.Key) == 0 || len(subNode.Value) == 0 {
-			log.Warnf("iceberg:ready etcd key=%s value=%s", string(subNode.Key), string(subNode.Value))
+			discoverLog.Warnf("iceberg:ready etcd key=%s value=%s", string(subNode.Key), string(subNode.Value))
 		} else {
 			discover.setTopo(string(subNode.Key), string(subNode.Value))
 		}
@@ -481,13 +653,13 @@ func (discover *Discover) discover() {
 		select {
 		case notify := <-ch:
 			if notify.Err() != nil {
-				log.Warn("iceberg:", notify.Err())
+				discoverLog.Warn("iceberg:", notify.Err())
 				continue
 			}
 			for _, event := range notify.Events {
 				key := string(event.Kv.Key)
 				value := string(event.Kv.Value)
-				log.Debugf("iceberg:watch event:%s key:%s value:%s leasid:%x",
+				discoverLog.Debugf("iceberg:watch event:%s key:%s value:%s leasid:%x",
 					event.Type.String(), key, value, event.Kv.Lease)
 				switch event.Type {
 				case clientv3.EventTypePut:
@@ -497,7 +669,7 @@ func (discover *Discover) discover() {
 				}
 			}
 		case <-discover.ctx.Done():
-			log.Infof("iceberg:dicover watch graceful exit.")
+			discoverLog.Infof("iceberg:dicover watch graceful exit.")
 			return
 		}
 	}
@@ -510,18 +682,47 @@ func (discover *Discover) setTopo(key, value string) {
 		return
 	}
 	if leafname := segment[segl-1]; leafname == "config" {
+		discover.setConfig(strings.Join(segment[:segl-2], "/"), value)
 
 	} else if leafname == "name" {
 
+	} else if leafname == "route" {
+		discover.setRoute(strings.Join(segment[:segl-2], "/"), value)
+
+	} else if leafname == "loglevel" {
+		discover.setLogLevel(strings.Join(segment[:segl-2], "/"), value)
+
+	} else if leafname == "methods" {
+		discover.setMethods(key, value)
+
+	} else if leafname == "acl" {
+		discover.setPolicy(strings.Join(segment[:segl-2], "/"), value)
+
 	} else if segment[segl-2] == "instances" {
 		interfaceURI := strings.Join(segment[:segl-3], "/")
 		discover.regist(interfaceURI, value)
 
+	} else if segment[segl-2] == "meta" {
+		discover.setMeta(segment[segl-1], value)
+
 	} else if segment[segl-2] == "allowed" {
 		discover.addMethod(key, value)
 	}
 }
 
+// setConfig 同步etcd中本服务的配置到动态配置中心
+// 其他服务的配置变化忽略
+func (discover *Discover) setConfig(URI, value string) {
+	if !discover.isSelf(URI) {
+		return
+	}
+	if err := config.Default().Update([]byte(value)); err != nil {
+		discoverLog.Errorf("iceberg:%s reject config update,detail=%s", URI, err.Error())
+		return
+	}
+	discoverLog.Infof("iceberg:%s config updated", URI)
+}
+
 func (discover *Discover) addMethod(mdkey, mdValue string) {
 	if len(mdkey) < len(root) {
 		return
@@ -532,19 +733,42 @@ func (discover *Discover) addMethod(mdkey, mdValue string) {
 	if nsl < 4 {
 		return
 	}
-	var md Medesc
-	if ns[nsl-1] == "true" {
-		md.Allowed = true
-	} else {
-		md.Allowed = false
-	}
+	mk := strings.Join(intercept(ns), "@")
+	discover.mtLocker.Lock()
+	md := discover.medesc(mk)
+	md.Allowed = ns[nsl-1] == "true"
 	md.MdName = mdValue
+	discover.mtLocker.Unlock()
+}
+
+// setMethods 方法接受的HTTP方法
+func (discover *Discover) setMethods(mdkey, mdValue string) {
+	ns := strings.Split(mdkey, "/")
+	if len(ns) < 5 {
+		return
+	}
+	var methods []string
+	for _, m := range strings.Split(mdValue, ",") {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			methods = append(methods, m)
+		}
+	}
 	mk := strings.Join(intercept(ns), "@")
 	discover.mtLocker.Lock()
-	discover.mdtables[mk] = &md
+	discover.medesc(mk).Methods = methods
 	discover.mtLocker.Unlock()
 }
 
+// medesc 方法描述，不存在时创建，调用者需持有mtLocker
+func (discover *Discover) medesc(mk string) *Medesc {
+	md := discover.mdtables[mk]
+	if md == nil {
+		md = new(Medesc)
+		discover.mdtables[mk] = md
+	}
+	return md
+}
+
 func (discover *Discover) delMethod(mdkey string) {
 	ns := strings.Split(mdkey, "/")
 	nsl := len(ns)
@@ -564,13 +788,23 @@ func (discover *Discover) rmTopo(key, value string) {
 		return
 	}
 	if leafname := segment[l-1]; leafname == "config" {
-		// TO DO
+		discover.setConfig(strings.Join(segment[:l-2], "/"), "")
 	} else if leafname == "name" {
 		// TO DO
+	} else if leafname == "route" {
+		discover.rmRoute(strings.Join(segment[:l-2], "/"))
+	} else if leafname == "loglevel" {
+		discover.setLogLevel(strings.Join(segment[:l-2], "/"), "")
+	} else if leafname == "methods" {
+		discover.setMethods(key, "")
+	} else if leafname == "acl" {
+		discover.setPolicy(strings.Join(segment[:l-2], "/"), "")
 	} else if segment[l-2] == "instances" {
 		interfaceURI := strings.Join(segment[:l-3], "/")
-		log.Debug("rmTopo:", interfaceURI, " ", segment[l-1])
+		discoverLog.Debug("rmTopo:", interfaceURI, " ", segment[l-1])
 		discover.unRegist(interfaceURI, segment[l-1])
+	} else if segment[l-2] == "meta" {
+		discover.rmMeta(segment[l-1])
 	} else if segment[l-2] == "allowed" {
 		// discover.delMethod(key)
 	}
@@ -584,7 +818,7 @@ func (discover *Discover) regist(URI string, svrAddr string) {
 
 	// 过滤掉监听到自己的状态变化产生的通知
 	if discover.localListenAddr == svrAddr {
-		log.Debugf("iceberg:discover self node changed %s", svrAddr)
+		discoverLog.Debugf("iceberg:discover self node changed %s", svrAddr)
 		return
 	}
 
@@ -596,11 +830,11 @@ func (discover *Discover) regist(URI string, svrAddr string) {
 	if topo, found = discover.topology[URI]; !found {
 		topo = NewConsistentHash()
 		discover.topology[URI] = topo
-		log.Debugf("Regist a new service at direction %s, the addr is %s", URI, svrAddr)
+		discoverLog.Debugf("Regist a new service at direction %s, the addr is %s", URI, svrAddr)
 	}
 
 	// 用后台服务的地址作为key来生成hash节点
-	log.Debugf("AddNode: %s svrAddr:%s", URI, svrAddr)
+	discoverLog.Debugf("AddNode: %s svrAddr:%s", URI, svrAddr)
 	topo.AddNode(svrAddr)
 }
 
@@ -616,8 +850,9 @@ func (discover *Discover) unRegist(URI string, nodeHashKey string) {
 		defer discover.topoLocker.Unlock()
 		if topo, found := discover.topology[URI]; found {
 			remoteAddr := topo.RmNode([]byte(nodeHashKey))
-			log.Debugf("Remove backend serve %s, nodeHashKey %s remoteAddr %s.",
+			discoverLog.Debugf("Remove backend serve %s, nodeHashKey %s remoteAddr %s.",
 				URI, nodeHashKey, remoteAddr)
+			discover.outlier.Forget(remoteAddr)
 			// 清掉已经建立的连接
 			if remoteAddr != "" {
 				if connactor, found := discover.connholder[remoteAddr]; found {
@@ -628,7 +863,7 @@ func (discover *Discover) unRegist(URI string, nodeHashKey string) {
 				}
 			}
 			if len(topo.nodeList) == 0 {
-				log.Debugf("Remove backend topology:%s", URI)
+				discoverLog.Debugf("Remove backend topology:%s", URI)
 				delete(discover.topology, URI)
 			}
 		}
@@ -636,7 +871,7 @@ func (discover *Discover) unRegist(URI string, nodeHashKey string) {
 		discover.topoLocker.Lock()
 		defer discover.topoLocker.Unlock()
 		if topo, found := discover.topology[URI]; found {
-			log.Infof("Remove all backend serve %s.", URI)
+			discoverLog.Infof("Remove all backend serve %s.", URI)
 			topo.Clear()
 			delete(discover.topology, URI)
 
@@ -661,7 +896,8 @@ func (discover *Discover) quit() {
 	for _, v := range discover.selfURI {
 		uri := v + "/provider/instances/" + discover.localListenAddr
 		discover.kapi.Delete(context.TODO(), uri)
-		log.Debugf("iceberg:%s quit delete etcd key:%s", discover.name, uri)
+		discoverLog.Debugf("iceberg:%s quit delete etcd key:%s", discover.name, uri)
+		discover.kapi.Delete(context.TODO(), v+"/provider/meta/"+discover.localListenAddr)
 	}
 
 	discover.kapi.Close()
@@ -673,4 +909,5 @@ func (discover *Discover) quit() {
 		}
 	}
 	discover.connLocker.RUnlock()
+	discover.closeTrace()
 }
@@ -85,10 +85,12 @@ func (shr *SignalHandler) handle(s os.Signal) {
 	if _, exist := shr.handlerMap[s]; exist {
 		if shr.handlerMap[s].Stop(s) {
 			Instance().quit()
+			log.Close()
 			os.Exit(0)
 		}
 	} else {
 		log.Errorf("Not found signal(%s)'s handler, exit.", s.String())
+		log.Close()
 		os.Exit(0)
 	}
 }
@@ -8,82 +8,110 @@ import (
 	"github.com/kwins/iceberg/frame/util"
 
 	"github.com/opentracing/opentracing-go"
-	"github.com/prometheus/common/log"
+	"github.com/opentracing/opentracing-go/ext"
 )
 
-// SpanWithTask 将Span注入Task
+// 链路追踪
+// gateway从HTTP Header中提取上游的Span，开启服务端Span并注入到Proto.TraceMap;
+// DeliverTo以TraceMap中的Span为父Span开启客户端Span，并将客户端Span注入TraceMap;
+// 服务端从TraceMap中提取Span开启服务端Span，放在Context.Ctx()中，ReadyTask再将其注入下游请求;
+// 没有设置全局Tracer时使用opentracing的NoopTracer，测试时可以使用mocktracer
+
+// SpanWithTask 以ctx中的Span为父Span开启客户端Span，并注入Task
 func SpanWithTask(ctx context.Context, task *protocol.Proto) opentracing.Span {
-	if tracer := opentracing.GlobalTracer(); tracer != nil {
-		var span opentracing.Span
-		parentSpan := opentracing.SpanFromContext(ctx)
-		if parentSpan != nil {
-			spf := opentracing.ChildOf(parentSpan.Context())
-			span = tracer.StartSpan(task.GetServeMethod(), spf)
-		} else {
-			span = tracer.StartSpan(task.GetServeMethod())
-		}
-		span.SetTag("Bizid", task.GetBizid())
-		span.SetTag("Host name", util.GetHostname())
-		tracer.Inject(span.Context(), opentracing.TextMap, task)
-		return span
+	tracer := opentracing.GlobalTracer()
+	var opts []opentracing.StartSpanOption
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
 	}
-	return nil
+	return startClientSpan(tracer, task, opts...)
 }
 
-// SpanFromTask 从Task中加工出Span
+// SpanFromTask 以Task中的Span为父Span开启服务端Span
 func SpanFromTask(task *protocol.Proto) opentracing.Span {
-	if tracer := opentracing.GlobalTracer(); tracer != nil {
-		var span opentracing.Span
-		spanCtx, err := tracer.Extract(opentracing.TextMap, task)
-		if err != nil {
-			span = tracer.StartSpan(task.GetServeMethod())
-		} else {
-			spf := opentracing.FollowsFrom(spanCtx)
-			span = tracer.StartSpan(task.GetServeMethod(), spf)
-		}
-		span.SetTag("Bizid", task.GetBizid())
-		span.SetTag("Host name", util.GetHostname())
-		return span
+	tracer := opentracing.GlobalTracer()
+	var opts []opentracing.StartSpanOption
+	if spanCtx, err := tracer.Extract(opentracing.TextMap, task); err == nil {
+		opts = append(opts, ext.RPCServerOption(spanCtx))
+	} else {
+		opts = append(opts, ext.SpanKindRPCServer)
 	}
-	return nil
+	span := tracer.StartSpan(task.GetServeURI()+"/"+task.GetServeMethod(), opts...)
+	setTaskTags(span, task)
+	return span
 }
 
 // SpanFromHTTPHeader 从HTTP请求中Extract Tracer信息 然后 Inject to Task
 func SpanFromHTTPHeader(header http.Header, task *protocol.Proto) opentracing.Span {
-	if tracer := opentracing.GlobalTracer(); tracer != nil {
-		var span opentracing.Span
-		wireContext, err := tracer.Extract(
-			opentracing.TextMap,
-			opentracing.HTTPHeadersCarrier(header),
-		)
-		if err != nil {
-			span = opentracing.StartSpan("server-http")
-		} else {
-			spf := opentracing.FollowsFrom(wireContext)
-			span = tracer.StartSpan("serve-http", spf)
-		}
-		span.SetTag("Bizid", task.GetBizid())
-		span.SetTag("Host name", util.GetHostname())
-		if err := tracer.Inject(span.Context(), opentracing.TextMap, task); err != nil {
-			log.Error(err.Error())
-			return nil
-		}
-		return span
+	tracer := opentracing.GlobalTracer()
+	var opts []opentracing.StartSpanOption
+	if wireContext, err := tracer.Extract(opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(header)); err == nil {
+		opts = append(opts, ext.RPCServerOption(wireContext))
+	} else {
+		opts = append(opts, ext.SpanKindRPCServer)
 	}
-	return nil
+	span := tracer.StartSpan("gateway "+task.GetServeURI(), opts...)
+	setTaskTags(span, task)
+	ext.HTTPMethod.Set(span, task.GetMethod().String())
+	if err := tracer.Inject(span.Context(), opentracing.TextMap, task); err != nil {
+		traceLog.Error(err.Error())
+	}
+	return span
 }
 
 // SpanFromContext 从Context中获取Span，然后Inject Request Header
 func SpanFromContext(ctx context.Context, h http.Header) opentracing.Span {
-	if tracer := opentracing.GlobalTracer(); tracer != nil {
-		var span opentracing.Span
-		if span = opentracing.SpanFromContext(ctx); span == nil {
-			span = opentracing.StartSpan("run")
+	tracer := opentracing.GlobalTracer()
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		span = tracer.StartSpan("run")
+	}
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(h)); err != nil {
+		return nil
+	}
+	return span
+}
+
+// FinishSpan 根据响应状态设置Span的状态并结束
+// status 响应状态码，err 不为空表示请求未完成
+func FinishSpan(span opentracing.Span, status int, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		ext.Error.Set(span, true)
+		span.LogKV("event", "error", "message", err.Error())
+	} else {
+		ext.HTTPStatusCode.Set(span, uint16(status))
+		if status >= http.StatusInternalServerError {
+			ext.Error.Set(span, true)
 		}
-		if err := tracer.Inject(span.Context(), opentracing.TextMap, h); err != nil {
-			return nil
+	}
+	span.Finish()
+}
+
+// startClientSpan 开启客户端Span，没有指定父Span时使用Task中已注入的Span
+func startClientSpan(tracer opentracing.Tracer, task *protocol.Proto,
+	opts ...opentracing.StartSpanOption) opentracing.Span {
+	if len(opts) == 0 {
+		if spanCtx, err := tracer.Extract(opentracing.TextMap, task); err == nil {
+			opts = append(opts, opentracing.ChildOf(spanCtx))
 		}
-		return span
 	}
-	return nil
+	opts = append(opts, ext.SpanKindRPCClient)
+	span := tracer.StartSpan(task.GetServeURI()+"/"+task.GetServeMethod(), opts...)
+	setTaskTags(span, task)
+	ext.PeerService.Set(span, task.GetServeURI())
+	if err := tracer.Inject(span.Context(), opentracing.TextMap, task); err != nil {
+		traceLog.Error(err.Error())
+	}
+	return span
+}
+
+func setTaskTags(span opentracing.Span, task *protocol.Proto) {
+	span.SetTag("bizid", task.GetBizid())
+	span.SetTag("request_id", task.GetRequestID())
+	span.SetTag("hostname", util.GetHostname())
 }
@@ -0,0 +1,80 @@
+package frame
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kwins/iceberg/frame/protocol"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+func TestTracePropagation(t *testing.T) {
+	tracer := mocktracer.New()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(opentracing.NoopTracer{})
+
+	// 上游调用方
+	upstream := tracer.StartSpan("upstream")
+	header := make(http.Header)
+	tracer.Inject(upstream.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+
+	// gateway
+	task := &protocol.Proto{ServeURI: "/services/v1/hello", ServeMethod: "sayhello", Bizid: "b1"}
+	gwSpan := SpanFromHTTPHeader(header, task)
+	client := startClientSpan(tracer, task)
+
+	// 服务端
+	var r protocol.Proto
+	b, _ := task.Serialize()
+	r.UnSerialize(b)
+	server := SpanFromTask(&r)
+	c := NewContext().(*icecontext)
+	c.Reset(&r, &protocol.Proto{})
+	c.ctx = opentracing.ContextWithSpan(c.ctx, server)
+
+	// 服务端调用下游
+	next, err := ReadyTask(c, "echo", "echo", "v1", map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	downstream := SpanWithTask(c.Ctx(), next)
+
+	FinishSpan(downstream, http.StatusOK, nil)
+	FinishSpan(server, http.StatusInternalServerError, nil)
+	FinishSpan(client, http.StatusOK, nil)
+	FinishSpan(gwSpan, http.StatusOK, nil)
+	upstream.Finish()
+
+	spans := tracer.FinishedSpans()
+	if len(spans) != 5 {
+		t.Fatalf("finished spans got %d", len(spans))
+	}
+	var byName = make(map[string]*mocktracer.MockSpan)
+	for _, s := range spans {
+		byName[s.OperationName] = s
+	}
+	gw := byName["gateway /services/v1/hello"]
+	if gw == nil || gw.ParentID != upstream.(*mocktracer.MockSpan).SpanContext.SpanID {
+		t.Errorf("gateway span should be child of upstream,%v", gw)
+	}
+	cs := client.(*mocktracer.MockSpan)
+	if cs.ParentID != gw.SpanContext.SpanID || cs.Tag(string(ext.SpanKind)) != ext.SpanKindRPCClientEnum {
+		t.Errorf("client span should be child of gateway,%v", cs)
+	}
+	ss := server.(*mocktracer.MockSpan)
+	if ss.ParentID != cs.SpanContext.SpanID || ss.Tag("error") != true {
+		t.Errorf("server span should be child of client,%v", ss)
+	}
+	ds := downstream.(*mocktracer.MockSpan)
+	if ds.ParentID != ss.SpanContext.SpanID {
+		t.Errorf("downstream span should be child of server,%v", ds)
+	}
+	for _, s := range spans {
+		if s.SpanContext.TraceID != gw.SpanContext.TraceID {
+			t.Errorf("span %s not in the same trace", s.OperationName)
+		}
+	}
+}
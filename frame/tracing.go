@@ -0,0 +1,133 @@
+package frame
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+	"github.com/kwins/iceberg/frame/util"
+
+	"github.com/opentracing/opentracing-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// 链路追踪后端
+// 框架内部统一使用opentracing接口埋点，后端可选:
+// otlp   通过OpenTelemetry的opentracing bridge把Span以OTLP(HTTP/gRPC)发送到collector，
+//        Proto.TraceMap和gateway的HTTP Header使用W3C traceparent/baggage传播;
+// zipkin 使用zipkin-go-opentracing，兼容原有的zipkinCfg配置;
+// 未配置时使用opentracing的NoopTracer
+
+const traceInstrumentation = "github.com/kwins/iceberg/frame"
+
+// startTrace 按配置启动链路追踪
+func (discover *Discover) startTrace(cfg *config.BaseCfg) error {
+	switch cfg.Trace.Backend {
+	case "otlp":
+		return discover.StartOTelTrace(cfg.Trace)
+	case "zipkin":
+		return discover.StartZipkinTrace(cfg.Trace.Endpoint, discover.localListenAddr, discover.name)
+	case "":
+		return discover.StartZipkinTrace(cfg.Zipkin.EndPoints, discover.localListenAddr, discover.name)
+	default:
+		return fmt.Errorf("unknown trace backend %s", cfg.Trace.Backend)
+	}
+}
+
+// StartOTelTrace 启动OpenTelemetry链路追踪，Span通过OTLP发送到collector
+func (discover *Discover) StartOTelTrace(cfg config.TraceCfg) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	exporter, err := otlptrace.New(ctx, newOTLPClient(cfg))
+	if err != nil {
+		return err
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(traceAttributes(discover.name, discover.version,
+			discover.localListenAddr, cfg.Attributes)...))
+	if err != nil {
+		return err
+	}
+	sampleRate := cfg.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRate))),
+	)
+	propagator := propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{})
+
+	bridge, wrapper := otbridge.NewTracerPair(tp.Tracer(traceInstrumentation))
+	bridge.SetTextMapPropagator(propagator)
+	bridge.SetWarningHandler(func(msg string) {
+		traceLog.Warnf("otel bridge:%s", msg)
+	})
+	// 原生OpenTelemetry埋点与opentracing埋点共享同一个调用链
+	otel.SetTracerProvider(wrapper)
+	otel.SetTextMapPropagator(propagator)
+	opentracing.SetGlobalTracer(bridge)
+	discover.traceShutdown = tp.Shutdown
+	traceLog.Infof("start otel trace endpoint:%s,protocol:%s,service:%s", cfg.Endpoint, cfg.Protocol, discover.name)
+	return nil
+}
+
+func newOTLPClient(cfg config.TraceCfg) otlptrace.Client {
+	if cfg.Protocol == "grpc" {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlptracegrpc.WithHeaders(cfg.Headers))
+		}
+		return otlptracegrpc.NewClient(opts...)
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	return otlptracehttp.NewClient(opts...)
+}
+
+// traceAttributes 服务的resource属性
+func traceAttributes(name, version, addr string, extra map[string]string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("service.name", name),
+		attribute.String("service.instance.id", addr),
+		attribute.String("host.name", util.GetHostname()),
+	}
+	if version != "" {
+		attrs = append(attrs, attribute.String("service.version", version))
+	}
+	for k, v := range extra {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return attrs
+}
+
+// closeTrace 退出前写出缓存的Span
+func (discover *Discover) closeTrace() {
+	if discover.traceShutdown == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := discover.traceShutdown(ctx); err != nil {
+		traceLog.Errorf("shutdown tracer fail,%s", err.Error())
+	}
+}
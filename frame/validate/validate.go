@@ -0,0 +1,114 @@
+// Package validate 请求参数校验
+// protoc-gen-go的irpc插件根据proto字段注释中的 @validate 规则为消息生成 Validate() error 方法，
+// 生成的代码使用本包的辅助函数，校验失败时返回 Errors，对应400响应并列出所有失败的字段
+package validate
+
+import (
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// Validator 可以校验自身的消息
+type Validator interface {
+	Validate() error
+}
+
+// Check 校验实现了Validator的值，未实现时返回nil
+func Check(v interface{}) error {
+	if vv, ok := v.(Validator); ok {
+		return vv.Validate()
+	}
+	return nil
+}
+
+// Errors 校验失败的字段
+type Errors []protocol.FieldError
+
+func (es Errors) Error() string {
+	var msgs = make([]string, 0, len(es))
+	for _, e := range es {
+		msgs = append(msgs, e.Field+e.Msg)
+	}
+	return "参数错误:" + strings.Join(msgs, ";")
+}
+
+// Status 响应状态码
+func (es Errors) Status() int {
+	return http.StatusBadRequest
+}
+
+// FieldErrors 校验失败的字段
+func (es Errors) FieldErrors() []protocol.FieldError {
+	return es
+}
+
+// Err 没有失败的字段时返回nil
+func (es Errors) Err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
+// Add 添加失败的字段
+func (es *Errors) Add(field, rule, msg string) {
+	*es = append(*es, protocol.FieldError{Field: field, Rule: rule, Msg: msg})
+}
+
+// Nested 合并嵌套消息的校验结果，字段名称加上前缀
+func (es *Errors) Nested(field string, err error) {
+	if err == nil {
+		return
+	}
+	nested, ok := err.(Errors)
+	if !ok {
+		es.Add(field, "message", err.Error())
+		return
+	}
+	for _, e := range nested {
+		e.Field = field + "." + e.Field
+		*es = append(*es, e)
+	}
+}
+
+// Index 数组元素的字段名称
+func Index(field string, i int) string {
+	return field + "[" + strconv.Itoa(i) + "]"
+}
+
+// RuneLen 字符串的字符数
+func RuneLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+var (
+	patterns      = make(map[string]*regexp.Regexp)
+	patternLocker sync.RWMutex
+)
+
+// Match 字符串是否匹配正则表达式，编译后的正则表达式会缓存;正则表达式不合法时总是不匹配
+func Match(pattern, s string) bool {
+	patternLocker.RLock()
+	re, ok := patterns[pattern]
+	patternLocker.RUnlock()
+	if !ok {
+		re, _ = regexp.Compile(pattern)
+		patternLocker.Lock()
+		patterns[pattern] = re
+		patternLocker.Unlock()
+	}
+	return re != nil && re.MatchString(s)
+}
+
+// IsEmail 是否是合法的邮箱地址
+func IsEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
@@ -0,0 +1,59 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+type addr struct{ city string }
+
+func (a *addr) Validate() error {
+	var errs Errors
+	if a.city == "" {
+		errs.Add("city", "required", "不能为空")
+	}
+	return errs.Err()
+}
+
+func TestErrors(t *testing.T) {
+	var errs Errors
+	if errs.Err() != nil {
+		t.Fatal("empty errors should be nil")
+	}
+	errs.Add("name", "required", "不能为空")
+	errs.Nested("addr", Check(&addr{}))
+	errs.Nested(Index("addrs", 1), Check(&addr{}))
+	errs.Nested("ok", Check(&addr{city: "sz"}))
+	if len(errs) != 3 {
+		t.Fatalf("want 3 field errors,got %v", errs)
+	}
+	if errs[1].Field != "addr.city" || errs[2].Field != "addrs[1].city" {
+		t.Error("nested field name fail", errs)
+	}
+	if errs.Status() != 400 {
+		t.Error("status should be 400")
+	}
+
+	var pro protocol.Proto
+	pro.FillErrInfo(errs.Status(), errs.Err())
+	t.Log(string(pro.Err))
+}
+
+func TestRules(t *testing.T) {
+	if !Match(`^[0-9]{6}$`, "123456") || Match(`^[0-9]{6}$`, "12345a") {
+		t.Error("match fail")
+	}
+	if Match(`^[0-9`, "123") || Match(`^[0-9`, "") {
+		t.Error("invalid pattern should not match")
+	}
+	if !IsEmail("a@b.com") || IsEmail("a.b.com") || IsEmail("A <a@b.com>") {
+		t.Error("email fail")
+	}
+	if RuneLen("冰山") != 2 {
+		t.Error("rune len fail")
+	}
+	if Check(1) != nil {
+		t.Error("non validator should pass")
+	}
+}
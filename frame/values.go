@@ -0,0 +1,157 @@
+package frame
+
+import (
+	goctx "context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// 请求级的键值对
+// 每个请求单独分配存储，Context被sync.Pool复用后，之前请求的Ctx()仍然引用自己的存储，不会读到下一个请求的值;
+// Ctx() 携带请求的超时时间，取消信号，链路追踪的Span和键值对，Ctx().Value(key)可以读取Set设置的值;
+// baggage 沿调用链传递，通过X-Iceberg-Baggage-前缀的Header发送给下游
+
+type ctxKey int
+
+const valuesKey ctxKey = iota
+
+// values 请求级的键值对和baggage
+type values struct {
+	locker  sync.RWMutex
+	bizid   string
+	m       map[string]interface{}
+	baggage map[string]string
+	md      Metadata // 发往下游的元数据
+}
+
+func newValues(r *protocol.Proto) *values {
+	vals := &values{bizid: r.GetBizid()}
+	for k, v := range r.GetHeader() {
+		if len(k) > len(protocol.HeaderXIcebergBaggagePrefix) &&
+			strings.EqualFold(k[:len(protocol.HeaderXIcebergBaggagePrefix)], protocol.HeaderXIcebergBaggagePrefix) {
+			vals.setBaggage(k[len(protocol.HeaderXIcebergBaggagePrefix):], v)
+		}
+	}
+	return vals
+}
+
+func (vals *values) get(key string) (interface{}, bool) {
+	vals.locker.RLock()
+	v, ok := vals.m[key]
+	vals.locker.RUnlock()
+	return v, ok
+}
+
+func (vals *values) set(key string, val interface{}) {
+	vals.locker.Lock()
+	if vals.m == nil {
+		vals.m = make(map[string]interface{})
+	}
+	vals.m[key] = val
+	vals.locker.Unlock()
+}
+
+func (vals *values) getBaggage(key string) string {
+	vals.locker.RLock()
+	defer vals.locker.RUnlock()
+	return vals.baggage[strings.ToLower(key)]
+}
+
+// setBaggage baggage的key不区分大小写，val为空表示删除
+func (vals *values) setBaggage(key, val string) {
+	vals.locker.Lock()
+	defer vals.locker.Unlock()
+	if val == "" {
+		delete(vals.baggage, strings.ToLower(key))
+		return
+	}
+	if vals.baggage == nil {
+		vals.baggage = make(map[string]string)
+	}
+	vals.baggage[strings.ToLower(key)] = val
+}
+
+func (vals *values) allBaggage() map[string]string {
+	vals.locker.RLock()
+	defer vals.locker.RUnlock()
+	var b = make(map[string]string, len(vals.baggage))
+	for k, v := range vals.baggage {
+		b[k] = v
+	}
+	return b
+}
+
+// valuesCtx 在go context中查找请求级的键值对
+type valuesCtx struct {
+	goctx.Context
+	vals *values
+}
+
+func (vc valuesCtx) Value(key interface{}) interface{} {
+	if key == valuesKey {
+		return vc.vals
+	}
+	if k, ok := key.(string); ok {
+		if v, ok := vc.vals.get(k); ok {
+			return v
+		}
+	}
+	return vc.Context.Value(key)
+}
+
+// BizidFromContext 获取Context.Ctx()中的Bizid
+func BizidFromContext(ctx goctx.Context) string {
+	if vals, ok := ctx.Value(valuesKey).(*values); ok {
+		return vals.bizid
+	}
+	return ""
+}
+
+// BaggageFromContext 获取Context.Ctx()中的baggage
+func BaggageFromContext(ctx goctx.Context) map[string]string {
+	if vals, ok := ctx.Value(valuesKey).(*values); ok {
+		return vals.allBaggage()
+	}
+	return nil
+}
+
+// requestTimeout 请求剩余的超时时间
+func requestTimeout(r *protocol.Proto) time.Duration {
+	v := headerValue(r.GetHeader(), protocol.HeaderXIcebergTimeout)
+	if v == "" {
+		return 0
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// injectContext 将ctx中的baggage和剩余超时时间写入下游请求的Header，已设置的baggage不覆盖
+func injectContext(ctx goctx.Context, task *protocol.Proto) error {
+	if err := ctx.Err(); err != nil {
+		if err == goctx.DeadlineExceeded {
+			return ErrTimeout
+		}
+		return err
+	}
+	for k, v := range BaggageFromContext(ctx) {
+		if headerValue(task.GetHeader(), protocol.HeaderXIcebergBaggagePrefix+k) == "" {
+			task.SetHeader(protocol.HeaderXIcebergBaggagePrefix+k, v)
+		}
+	}
+	if d, ok := ctx.Deadline(); ok {
+		remain := time.Until(d)
+		if old := requestTimeout(task); old > 0 && old < remain {
+			remain = old
+		}
+		task.SetHeader(protocol.HeaderXIcebergTimeout,
+			strconv.FormatInt(int64(remain/time.Millisecond)+1, 10))
+	}
+	return nil
+}
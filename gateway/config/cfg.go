@@ -1,6 +1,10 @@
 package config
 
-import "github.com/kwins/iceberg/frame/config"
+import (
+	"time"
+
+	"github.com/kwins/iceberg/frame/config"
+)
 
 // Config 对应配置文件中的格式定义
 type Config struct {
@@ -8,7 +12,65 @@ type Config struct {
 	Authorization bool            `json:"authorization"`
 	IP            string          `json:"ip"`
 	Port          string          `json:"port"`
+	Timeout       int             `json:"timeout"` // 转发请求的超时时间，单位毫秒，默认10000
 	Base          config.BaseCfg  `json:"baseCfg"`
 	Redis         config.RedisCfg `json:"redisCfg"`
 	Mysql         config.MysqlCfg `json:"mysqlCfg"`
+	Upload        UploadCfg       `json:"uploadCfg"`
+	CORS          CORSCfg         `json:"corsCfg"`
+	Admin         AdminCfg        `json:"adminCfg"`
+}
+
+// AdminCfg 管理接口(/instances,/loglevel等)的访问控制，本机总是可以访问
+type AdminCfg struct {
+	Allow []string `json:"allow"` // 允许访问的IP或CIDR
+}
+
+// CORSCfg 跨域配置，AllowOrigins为空时不处理跨域请求
+type CORSCfg struct {
+	AllowOrigins     []string `json:"allow_origins"`     // 允许的Origin，支持 * 和 https://*.example.com
+	AllowMethods     []string `json:"allow_methods"`     // 允许的方法，默认GET,POST,PUT,PATCH,DELETE
+	AllowHeaders     []string `json:"allow_headers"`     // 允许的请求Header，为空时允许预检请求中的所有Header
+	ExposeHeaders    []string `json:"expose_headers"`    // 浏览器可以读取的响应Header
+	AllowCredentials bool     `json:"allow_credentials"` // 是否允许携带Cookie
+	MaxAge           int      `json:"max_age"`           // 预检结果的缓存时间，单位秒
+
+	// URIs 按服务URI前缀覆盖全局配置，如 /services/v1/hello
+	URIs map[string]CORSCfg `json:"uris"`
+}
+
+// UploadCfg multipart上传文件的大小限制，单位MB，为0时使用默认值
+type UploadCfg struct {
+	MaxRequestSize int64 `json:"max_request_size"` // 整个请求的大小，默认32MB
+	MaxFileSize    int64 `json:"max_file_size"`    // 单个文件的大小，默认与MaxRequestSize相同
+}
+
+// 默认的转发超时时间，单位毫秒
+const defaultTimeout = 10000
+
+// RequestTimeout 转发请求的超时时间
+func (cfg Config) RequestTimeout() time.Duration {
+	if cfg.Timeout <= 0 {
+		return defaultTimeout * time.Millisecond
+	}
+	return time.Duration(cfg.Timeout) * time.Millisecond
+}
+
+// 默认的上传大小限制
+const defaultMaxRequestSize = 32
+
+// RequestLimit 请求大小限制，单位bytes
+func (cfg UploadCfg) RequestLimit() int64 {
+	if cfg.MaxRequestSize <= 0 {
+		return defaultMaxRequestSize << 20
+	}
+	return cfg.MaxRequestSize << 20
+}
+
+// FileLimit 单个文件大小限制，单位bytes
+func (cfg UploadCfg) FileLimit() int64 {
+	if cfg.MaxFileSize <= 0 {
+		return cfg.RequestLimit()
+	}
+	return cfg.MaxFileSize << 20
 }
@@ -9,12 +9,13 @@ import (
 
 	"github.com/kwins/iceberg/frame"
 	"github.com/kwins/iceberg/frame/config"
+	log "github.com/kwins/iceberg/frame/icelog"
 	gwcfg "github.com/kwins/iceberg/gateway/config"
 	"github.com/kwins/iceberg/gateway/serve"
 )
 
 var (
-	cfgFile  = flag.String("config-path", "gw.json", "config file")
+	cfgFile  = flag.String("config-path", "gw.json", "config file,support json,yaml and toml")
 	logLevel = flag.String("level", "debug", "log level")
 	logPath  = flag.String("log-path", "", "log path")
 )
@@ -27,7 +28,9 @@ func main() {
 
 	// 读取并解析配置文件
 	var cfg gwcfg.Config
-	config.Parseconfig(*cfgFile, &cfg)
+	if err := config.Load(*cfgFile, &cfg); err != nil {
+		log.Fatalf("load config %s fail,%s", *cfgFile, err.Error())
+	}
 
 	s := serve.NewGateway(cfg)
 	sh := frame.NewSignalHandler()
@@ -0,0 +1,187 @@
+package serve
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/kwins/iceberg/frame/icelog"
+	"github.com/kwins/iceberg/frame/protocol"
+	gcfg "github.com/kwins/iceberg/gateway/config"
+)
+
+// 跨域
+// 预检请求(OPTIONS并带有Access-Control-Request-Method)由gateway直接响应，不转发到服务;
+// 实际请求在转发前加上Access-Control-Allow-Origin等Header，服务设置的同名Header优先
+
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// corsPolicy 一组跨域规则
+type corsPolicy struct {
+	origins     []string
+	methods     []string
+	headers     string
+	expose      string
+	credentials bool
+	maxAge      string
+}
+
+// uriPolicy 服务URI的跨域规则
+type uriPolicy struct {
+	prefix string
+	policy *corsPolicy
+}
+
+// cors 跨域处理
+type cors struct {
+	global *corsPolicy
+	uris   []uriPolicy // 按前缀长度从长到短排列
+}
+
+// newCORS 没有任何跨域配置时返回nil
+func newCORS(cfg gcfg.CORSCfg) *cors {
+	if len(cfg.AllowOrigins) == 0 && len(cfg.URIs) == 0 {
+		return nil
+	}
+	c := &cors{global: newCORSPolicy(cfg)}
+	for prefix, uc := range cfg.URIs {
+		c.uris = append(c.uris, uriPolicy{prefix: prefix, policy: newCORSPolicy(uc)})
+	}
+	sort.Slice(c.uris, func(i, j int) bool {
+		return len(c.uris[i].prefix) > len(c.uris[j].prefix)
+	})
+	return c
+}
+
+// newCORSPolicy AllowOrigins为空时返回nil，表示不允许跨域;
+// 允许任意Origin(*)时不允许携带Cookie，否则任意站点都能以用户身份发起请求
+func newCORSPolicy(cfg gcfg.CORSCfg) *corsPolicy {
+	if len(cfg.AllowOrigins) == 0 {
+		return nil
+	}
+	p := &corsPolicy{credentials: cfg.AllowCredentials}
+	for _, o := range cfg.AllowOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		if o == "*" && p.credentials {
+			log.Warn("cors allow_origins * can not be used with allow_credentials, credentials disabled")
+			p.credentials = false
+		}
+		p.origins = append(p.origins, o)
+	}
+	methods := cfg.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	for _, m := range methods {
+		p.methods = append(p.methods, strings.ToUpper(strings.TrimSpace(m)))
+	}
+	p.headers = strings.Join(cfg.AllowHeaders, ", ")
+	p.expose = strings.Join(cfg.ExposeHeaders, ", ")
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(cfg.MaxAge)
+	}
+	return p
+}
+
+// policy 请求路径对应的跨域规则
+func (c *cors) policy(path string) *corsPolicy {
+	for _, up := range c.uris {
+		if matchPrefix(path, up.prefix) {
+			return up.policy
+		}
+	}
+	return c.global
+}
+
+// matchPrefix 路径是否在前缀下，按路径段匹配，/v1/user不匹配/v1/users
+func matchPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// allowOrigin 是否允许该Origin
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, o := range p.origins {
+		if o == "*" || o == origin {
+			return true
+		}
+		if i := strings.IndexByte(o, '*'); i != -1 {
+			prefix, suffix := o[:i], o[i+1:]
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowMethod 是否允许该方法
+func (p *corsPolicy) allowMethod(method string) bool {
+	method = strings.ToUpper(method)
+	for _, m := range p.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// setOrigin 设置允许的Origin，允许携带Cookie时不能使用*
+func (p *corsPolicy) setOrigin(h http.Header, origin string) {
+	if len(p.origins) == 1 && p.origins[0] == "*" && !p.credentials {
+		h.Set(protocol.HeaderAccessControlAllowOrigin, "*")
+	} else {
+		h.Set(protocol.HeaderAccessControlAllowOrigin, origin)
+		h.Add(protocol.HeaderVary, protocol.HeaderOrigin)
+	}
+	if p.credentials {
+		h.Set(protocol.HeaderAccessControlAllowCredentials, "true")
+	}
+}
+
+// handle 处理跨域请求，返回true表示是预检请求且已响应
+func (c *cors) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get(protocol.HeaderOrigin)
+	if origin == "" {
+		return false
+	}
+	p := c.policy(r.URL.Path)
+	reqMethod := r.Header.Get(protocol.HeaderAccessControlRequestMethod)
+	preflight := r.Method == http.MethodOptions && reqMethod != ""
+	h := w.Header()
+
+	if !preflight {
+		if p != nil && p.allowOrigin(origin) {
+			p.setOrigin(h, origin)
+			if p.expose != "" {
+				h.Set(protocol.HeaderAccessControlExposeHeaders, p.expose)
+			}
+		}
+		return false
+	}
+
+	if p == nil || !p.allowOrigin(origin) || !p.allowMethod(reqMethod) {
+		log.Debugf("cors preflight rejected,origin=%s method=%s path=%s", origin, reqMethod, r.URL.Path)
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+	p.setOrigin(h, origin)
+	h.Add(protocol.HeaderVary, protocol.HeaderAccessControlRequestMethod)
+	h.Add(protocol.HeaderVary, protocol.HeaderAccessControlRequestHeaders)
+	h.Set(protocol.HeaderAccessControlAllowMethods, strings.Join(p.methods, ", "))
+	if p.headers != "" {
+		h.Set(protocol.HeaderAccessControlAllowHeaders, p.headers)
+	} else if reqHeaders := r.Header.Get(protocol.HeaderAccessControlRequestHeaders); reqHeaders != "" {
+		h.Set(protocol.HeaderAccessControlAllowHeaders, reqHeaders)
+	}
+	if p.maxAge != "" {
+		h.Set(protocol.HeaderAccessControlMaxAge, p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,81 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kwins/iceberg/frame/protocol"
+	gcfg "github.com/kwins/iceberg/gateway/config"
+)
+
+func TestCORS(t *testing.T) {
+	c := newCORS(gcfg.CORSCfg{
+		AllowOrigins:  []string{"https://*.example.com"},
+		ExposeHeaders: []string{"X-Total"},
+		MaxAge:        600,
+		URIs: map[string]gcfg.CORSCfg{
+			"/services/v1/pay":      {AllowOrigins: []string{"https://pay.example.com"}, AllowMethods: []string{"POST"}, AllowCredentials: true},
+			"/services/v1/internal": {},
+		},
+	})
+
+	preflight := func(path, origin, method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", path, nil)
+		r.Header.Set(protocol.HeaderOrigin, origin)
+		r.Header.Set(protocol.HeaderAccessControlRequestMethod, method)
+		r.Header.Set(protocol.HeaderAccessControlRequestHeaders, "X-Token")
+		w := httptest.NewRecorder()
+		if !c.handle(w, r) {
+			t.Fatalf("preflight %s should be answered", path)
+		}
+		return w
+	}
+
+	w := preflight("/services/v1/hello/sayhello", "https://app.example.com", "PUT")
+	if w.Code != http.StatusNoContent || w.Header().Get(protocol.HeaderAccessControlAllowOrigin) != "https://app.example.com" ||
+		w.Header().Get(protocol.HeaderAccessControlAllowHeaders) != "X-Token" || w.Header().Get(protocol.HeaderAccessControlMaxAge) != "600" {
+		t.Errorf("preflight fail,%d %v", w.Code, w.Header())
+	}
+	if w := preflight("/services/v1/hello/sayhello", "https://example.com.evil.org", "GET"); w.Code != http.StatusForbidden {
+		t.Errorf("origin should be rejected,%d", w.Code)
+	}
+	if w := preflight("/services/v1/pay/create", "https://pay.example.com", "GET"); w.Code != http.StatusForbidden {
+		t.Errorf("method should be rejected by uri policy,%d", w.Code)
+	}
+	w = preflight("/services/v1/pay/create", "https://pay.example.com", "POST")
+	if w.Code != http.StatusNoContent || w.Header().Get(protocol.HeaderAccessControlAllowCredentials) != "true" {
+		t.Errorf("uri policy fail,%d %v", w.Code, w.Header())
+	}
+	if w := preflight("/services/v1/internal/x", "https://app.example.com", "GET"); w.Code != http.StatusForbidden {
+		t.Errorf("uri without origins should disable cors,%d", w.Code)
+	}
+	// 前缀按路径段匹配，/services/v1/payment不使用/services/v1/pay的规则
+	if w := preflight("/services/v1/payment/create", "https://app.example.com", "PUT"); w.Code != http.StatusNoContent {
+		t.Errorf("uri prefix should match whole segments,%d", w.Code)
+	}
+
+	// 实际请求只添加Header，继续转发
+	r := httptest.NewRequest("GET", "/services/v1/hello/sayhello", nil)
+	r.Header.Set(protocol.HeaderOrigin, "https://app.example.com")
+	w = httptest.NewRecorder()
+	if c.handle(w, r) {
+		t.Fatal("actual request should be forwarded")
+	}
+	if w.Header().Get(protocol.HeaderAccessControlAllowOrigin) != "https://app.example.com" ||
+		w.Header().Get(protocol.HeaderAccessControlExposeHeaders) != "X-Total" {
+		t.Errorf("actual request headers fail,%v", w.Header())
+	}
+}
+
+func TestCORSWildcardCredentials(t *testing.T) {
+	c := newCORS(gcfg.CORSCfg{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	r := httptest.NewRequest("GET", "/services/v1/hello/sayhello", nil)
+	r.Header.Set(protocol.HeaderOrigin, "https://evil.org")
+	w := httptest.NewRecorder()
+	c.handle(w, r)
+	if w.Header().Get(protocol.HeaderAccessControlAllowOrigin) != "*" ||
+		w.Header().Get(protocol.HeaderAccessControlAllowCredentials) != "" {
+		t.Errorf("wildcard origin must not allow credentials,%v", w.Header())
+	}
+}
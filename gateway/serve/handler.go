@@ -1,8 +1,10 @@
 package serve
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/kwins/iceberg/frame"
 	log "github.com/kwins/iceberg/frame/icelog"
@@ -23,6 +25,54 @@ func HandleStatics(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleInstances 服务实例及元数据查询
+// /instances?uri=/services/v1/hello
+func HandleInstances(w http.ResponseWriter, r *http.Request) {
+	ins := frame.Instance().Instances(r.URL.Query().Get("uri"))
+	if b, err := json.Marshal(ins); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else {
+		w.Write(b)
+	}
+}
+
+// HandleLogLevel 查看和修改日志级别
+// GET /loglevel 查看网关的日志级别
+// POST /loglevel?level=debug&name=frame 修改网关的日志级别，name为空表示全局级别
+// POST /loglevel?uri=/services/v1/hello&level=debug 修改服务所有实例的日志级别，level为空恢复启动时的级别
+func HandleLogLevel(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		var err error
+		level := r.FormValue("level")
+		if uri := r.FormValue("uri"); uri != "" {
+			err = frame.Instance().PutLogLevel(uri, level)
+		} else {
+			err = log.SetNamedLevel(r.FormValue("name"), level)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if b, err := json.Marshal(log.Levels()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else {
+		w.Write(b)
+	}
+}
+
+// adminOnly 管理接口只允许本机和adminCfg中配置的客户端访问
+func (gw *Gateway) adminOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !gw.isAdmin(r.RemoteAddr) {
+			log.Warnf("admin api %s forbidden,ip:%s", r.URL.Path, r.RemoteAddr)
+			http.Error(w, errForbidden, http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
+
 // HandleNotFound http 404
 func HandleNotFound(w http.ResponseWriter, r *http.Request) {
 	log.Infof("not found url:%s ip:%s", r.URL.Path, r.RemoteAddr)
@@ -31,34 +81,30 @@ func HandleNotFound(w http.ResponseWriter, r *http.Request) {
 
 // HandleIceberg iceberg 服务入口
 func (gw *Gateway) HandleIceberg(w http.ResponseWriter, r *http.Request) {
-
-	if task, err := resolveRequest(r); err != nil {
+	var start = time.Now()
+	if declared, found := frame.Instance().Methods(r.URL.Path); handleMethod(w, r, declared, found) {
+		return
+	}
+	if task, err := resolveRequest(r, gw.cfg.Upload); err != nil {
 		log.Error(err.Error())
-		http.Error(w, errRequestInvalide, http.StatusBadRequest)
+		if err == errBodyTooLarge {
+			http.Error(w, errRequestTooLarge, http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, errRequestInvalide, http.StatusBadRequest)
+		}
 
 	} else {
-		log.Info(task.AsString())
-		// 转发到具体服务
-		resp, err := frame.DeliverTo(task)
-		if err != nil {
-			log.Warn(err.Error())
-			if err == frame.ErrTimeout {
-				http.Error(w, errGatewayTimeout, http.StatusGatewayTimeout)
-			} else {
-				http.Error(w, errInternalError, http.StatusInternalServerError)
-			}
-		} else {
-			log.Info(resp.AsString())
-			if len(resp.GetBody()) > 0 {
-				for k, v := range resp.GetHeader() {
-					w.Header().Set(k, v)
-				}
-				w.Write(resp.GetBody())
-			} else if len(resp.GetErr()) > 0 {
-				http.Error(w, string(resp.Err), http.StatusInternalServerError)
-			} else {
-				http.Error(w, errInternalError, http.StatusInternalServerError)
-			}
+		if !gw.isTrusted(r.RemoteAddr) {
+			frame.StripMetadata(task)
+			stripInternal(task)
 		}
+		span := frame.SpanFromHTTPHeader(r.Header, task)
+		// 转发到具体服务，客户端的X-Iceberg-Timeout只能缩短网关的超时时间
+		ctx, cancel := context.WithTimeout(r.Context(), gw.cfg.RequestTimeout())
+		resp, err := frame.DeliverTo(task, frame.WithContext(ctx))
+		cancel()
+		status := writeResult(w, r, resp, err)
+		frame.FinishSpan(span, status, nil)
+		frame.LogAccess(task, resp, status, time.Since(start))
 	}
 }
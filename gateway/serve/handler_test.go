@@ -0,0 +1,28 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminOnly(t *testing.T) {
+	gw := &Gateway{admins: parseTrusted([]string{"10.0.0.0/8"})}
+	h := gw.adminOnly(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	for addr, want := range map[string]int{
+		"127.0.0.1:5000":   http.StatusOK,
+		"[::1]:5000":       http.StatusOK,
+		"10.2.3.4:5000":    http.StatusOK,
+		"203.0.113.9:5000": http.StatusForbidden,
+	} {
+		r := httptest.NewRequest("GET", "/instances", nil)
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h(w, r)
+		if w.Code != want {
+			t.Errorf("%s want %d,got %d", addr, want, w.Code)
+		}
+	}
+}
@@ -7,6 +7,9 @@ import (
 
 var errGatewayTimeout = `{"errcode":504,"errmsg":"请求超时"}`
 var errRequestInvalide = `{"errcode":400,"errmsg":"请求无效"}`
+var errRequestTooLarge = `{"errcode":413,"errmsg":"上传数据过大"}`
+var errForbidden = `{"errcode":403,"errmsg":"没有访问权限"}`
 var errAuthFail = `{"errcode":-1002,"errmsg":"认证失败"}`
+var errMethodNotAllowed = `{"errcode":405,"errmsg":"不支持的请求方法"}`
 var errNotFounHTTPMethod = `{"errcode":404,"errmsg":"资源不存在"}`
 var errInternalError = `{"errcode":500,"errmsg":"服务器开了点小差，请稍后再试～"}`
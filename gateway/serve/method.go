@@ -0,0 +1,66 @@
+package serve
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kwins/iceberg/frame/protocol"
+)
+
+// HTTP方法
+// 服务在proto中用 @methods 声明每个方法接受的HTTP方法，没有声明时接受所有支持的方法;
+// 不接受的方法返回405和Allow;声明了GET时也接受HEAD，HEAD请求转发到服务，响应去掉响应体;
+// OPTIONS请求(跨域预检除外)由gateway直接返回204和Allow，方法没有注册时返回404
+
+// defaultAllowMethods 服务没有声明时接受的方法
+var defaultAllowMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// allowMethods 路由接受的方法，declared为服务声明的方法
+func allowMethods(declared []string) []string {
+	if len(declared) == 0 {
+		return defaultAllowMethods
+	}
+	var methods []string
+	var get, head bool
+	for _, m := range declared {
+		switch m {
+		case http.MethodOptions:
+			continue
+		case http.MethodGet:
+			get = true
+		case http.MethodHead:
+			head = true
+		}
+		methods = append(methods, m)
+	}
+	if get && !head {
+		methods = append(methods, http.MethodHead)
+	}
+	return append(methods, http.MethodOptions)
+}
+
+// handleMethod 检查请求方法，返回true表示已响应
+// found 方法是否已注册
+func handleMethod(w http.ResponseWriter, r *http.Request, declared []string, found bool) bool {
+	allow := allowMethods(declared)
+	if r.Method == http.MethodOptions {
+		if !found {
+			HandleNotFound(w, r)
+			return true
+		}
+		w.Header().Set(protocol.HeaderAllow, strings.Join(allow, ", "))
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+	for _, m := range allow {
+		if m == r.Method {
+			return false
+		}
+	}
+	w.Header().Set(protocol.HeaderAllow, strings.Join(allow, ", "))
+	http.Error(w, errMethodNotAllowed, http.StatusMethodNotAllowed)
+	return true
+}
@@ -0,0 +1,60 @@
+package serve
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowMethods(t *testing.T) {
+	if got := allowMethods(nil); len(got) != len(defaultAllowMethods) {
+		t.Errorf("undeclared should allow all,got %v", got)
+	}
+	got := allowMethods([]string{"GET", "POST", "OPTIONS"})
+	want := []string{"GET", "POST", "HEAD", "OPTIONS"}
+	if len(got) != len(want) {
+		t.Fatalf("want %v,got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("want %v,got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestHandleMethod(t *testing.T) {
+	declared := []string{"POST", "PATCH"}
+
+	w := httptest.NewRecorder()
+	if handleMethod(w, httptest.NewRequest("PATCH", "/services/v1/hello/update", nil), declared, true) {
+		t.Error("declared method should pass")
+	}
+
+	w = httptest.NewRecorder()
+	if !handleMethod(w, httptest.NewRequest("GET", "/services/v1/hello/update", nil), declared, true) ||
+		w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("undeclared method should be 405,got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "POST, PATCH, OPTIONS" {
+		t.Errorf("allow header fail,%q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	if !handleMethod(w, httptest.NewRequest("OPTIONS", "/services/v1/hello/update", nil), declared, true) ||
+		w.Code != http.StatusNoContent || w.Header().Get("Allow") != "POST, PATCH, OPTIONS" {
+		t.Errorf("options fail,%d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	if !handleMethod(w, httptest.NewRequest("TRACE", "/services/v1/hello/update", nil), nil, true) ||
+		w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("unsupported method should be 405,got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	if !handleMethod(w, httptest.NewRequest("OPTIONS", "/services/v1/hello/nothing", nil), nil, false) ||
+		w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+		t.Errorf("options of unknown method should be 404,got %d", w.Code)
+	}
+}
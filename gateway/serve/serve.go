@@ -1,6 +1,7 @@
 package serve
 
 import (
+	"encoding/json"
 	"net"
 	"net/http"
 	"os"
@@ -9,6 +10,9 @@ import (
 
 	"github.com/kwins/iceberg/frame"
 	log "github.com/kwins/iceberg/frame/icelog"
+	"github.com/kwins/iceberg/frame/protocol"
+
+	"github.com/nobugtodebug/go-objectid"
 )
 
 var root = "/services"
@@ -18,6 +22,9 @@ type Gateway struct {
 	cfg        gcfg.Config
 	listenAddr string
 	rt         *Router
+	trusted    []*net.IPNet // 可信客户端，请求中的元数据Header不删除
+	admins     []*net.IPNet // 可以访问管理接口的客户端
+	cors       *cors        // 跨域处理，没有配置时为nil
 }
 
 // NewGateway 网关
@@ -28,9 +35,15 @@ func NewGateway(cfg gcfg.Config) *Gateway {
 	gw.listenAddr = frame.Netip() + ":" + gw.cfg.Port
 	frame.Instance().Start("Gateway", &gw.cfg.Base, []string{root}, gw.listenAddr)
 
+	gw.trusted = parseTrusted(gw.cfg.Base.Metadata.TrustedProxies)
+	gw.admins = parseTrusted(gw.cfg.Admin.Allow)
+	gw.cors = newCORS(gw.cfg.CORS)
+
 	gw.rt = NewRouter(gw.HandleIceberg, HandleNotFound)
 	gw.rt.Add("/ping", HandlePing)
 	gw.rt.Add("/statistics", HandleStatics)
+	gw.rt.Add("/instances", gw.adminOnly(HandleInstances))
+	gw.rt.Add("/loglevel", gw.adminOnly(HandleLogLevel))
 
 	log.Debugf("gateway init with cfg=%v", gw.cfg)
 	return gw
@@ -53,6 +66,24 @@ func (gw *Gateway) Stop(s os.Signal) bool {
 
 // ServeHTTP implement http ServeHTTP
 // 服务入口，转发到来的所有请求到具体服务
+// 处理请求时发生panic返回500和bizid
 func (gw *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("bizid") == "" {
+		r.Header.Set("bizid", objectid.New().String())
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			pe := frame.RecoverPanic(rec, &protocol.Proto{
+				Bizid:      r.Header.Get("bizid"),
+				ServeURI:   r.URL.Path,
+				RemoteAddr: r.RemoteAddr,
+			})
+			b, _ := json.Marshal(&protocol.ErrInfo{ErrCode: pe.Status(), ErrInfo: pe.Error()})
+			http.Error(w, string(b), pe.Status())
+		}
+	}()
+	if gw.cors != nil && gw.cors.handle(w, r) {
+		return
+	}
 	gw.rt.Hanlder(r.URL.Path)(w, r)
 }
@@ -1,20 +1,32 @@
 package serve
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/kwins/iceberg/frame"
+	log "github.com/kwins/iceberg/frame/icelog"
 	"github.com/kwins/iceberg/frame/protocol"
+	gcfg "github.com/kwins/iceberg/gateway/config"
 
 	"github.com/nobugtodebug/go-objectid"
 )
 
-var defaultMemory = int64(32 >> 22)
+// defaultMemory multipart表单在内存中解析的大小，超过的部分写入临时文件
+var defaultMemory = int64(32 << 20)
 
-func resolveRequest(r *http.Request) (*protocol.Proto, error) {
+// errBodyTooLarge 上传的请求或文件超过大小限制
+var errBodyTooLarge = errors.New("request body too large")
+
+func resolveRequest(r *http.Request, upload gcfg.UploadCfg) (*protocol.Proto, error) {
 	// 准备Iceberg通用协议
 	var task protocol.Proto
 	businessID := r.Header.Get("bizid")
@@ -46,16 +58,11 @@ func resolveRequest(r *http.Request) (*protocol.Proto, error) {
 
 	// 解析Header信息
 	task.Header = make(map[string]string)
-	for k := range r.Header {
-		task.Header[k] = r.Header.Get(k)
-	}
+	task.SetHTTPHeader(r.Header)
 
 	// URL RAW Query data
 	task.Form = make(map[string]string)
-	q := r.URL.Query()
-	for k := range q {
-		task.Form[k] = q.Get(k)
-	}
+	task.SetURLValues(r.URL.Query())
 	// 解析Form，Body信息
 	contentType := r.Header.Get(protocol.HeaderContentType)
 	if strings.HasPrefix(contentType, protocol.MIMEApplicationJSON) {
@@ -70,9 +77,23 @@ func resolveRequest(r *http.Request) (*protocol.Proto, error) {
 
 	} else {
 		if strings.HasPrefix(contentType, protocol.MIMEMultipartForm) {
+			if r.ContentLength > upload.RequestLimit() {
+				return nil, errBodyTooLarge
+			}
+			body := &limitedBody{ReadCloser: r.Body, remain: upload.RequestLimit()}
+			r.Body = body
 			if err := r.ParseMultipartForm(defaultMemory); err != nil {
+				if body.exceeded {
+					return nil, errBodyTooLarge
+				}
 				return nil, err
 			}
+			defer r.MultipartForm.RemoveAll()
+			files, err := readFormFiles(r.MultipartForm, upload.FileLimit())
+			if err != nil {
+				return nil, err
+			}
+			task.Files = files
 		} else if strings.HasPrefix(contentType, protocol.MIMEApplicationForm) {
 			if err := r.ParseForm(); err != nil {
 				return nil, err
@@ -80,9 +101,7 @@ func resolveRequest(r *http.Request) (*protocol.Proto, error) {
 		}
 
 		if len(r.Form) > 0 {
-			for k := range r.Form {
-				task.Form[k] = r.Form.Get(k)
-			}
+			task.SetURLValues(r.Form)
 			task.Format = protocol.RestfulFormat_RAWQUERY
 		} else {
 			task.Format = protocol.RestfulFormat_FORMATNULL
@@ -94,3 +113,183 @@ func resolveRequest(r *http.Request) (*protocol.Proto, error) {
 
 	return &task, nil
 }
+
+// limitedBody 限制读取的请求大小，超过时返回errBodyTooLarge并记录
+type limitedBody struct {
+	io.ReadCloser
+	remain   int64
+	exceeded bool
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, errBodyTooLarge
+	}
+	// 多读一个字节判断是否超过限制
+	if int64(len(p)) > l.remain+1 {
+		p = p[:l.remain+1]
+	}
+	n, err := l.ReadCloser.Read(p)
+	if int64(n) > l.remain {
+		l.exceeded = true
+		return int(l.remain), errBodyTooLarge
+	}
+	l.remain -= int64(n)
+	return n, err
+}
+
+// readFormFiles 读取multipart表单中上传的文件
+func readFormFiles(form *multipart.Form, limit int64) ([]*protocol.FormFile, error) {
+	var files []*protocol.FormFile
+	for field, fhs := range form.File {
+		for _, fh := range fhs {
+			if fh.Size > limit {
+				return nil, errBodyTooLarge
+			}
+			f, err := fh.Open()
+			if err != nil {
+				return nil, err
+			}
+			content, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, &protocol.FormFile{
+				Field:       field,
+				Filename:    fh.Filename,
+				ContentType: fh.Header.Get(protocol.HeaderContentType),
+				Size:        int64(len(content)),
+				Content:     content,
+			})
+		}
+	}
+	return files, nil
+}
+
+// stripInternal 删除客户端请求中框架内部使用的X-Iceberg-* Header，如调试标记
+func stripInternal(task *protocol.Proto) {
+	for k := range task.GetHeader() {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), protocol.HeaderXIcebergPrefix) {
+			task.DelHeader(k)
+		}
+	}
+}
+
+// parseTrusted 解析客户端的IP或CIDR
+func parseTrusted(addrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+				addr += "/32"
+			} else {
+				addr += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(addr)
+		if err != nil {
+			log.Errorf("bad ip or cidr %s,%s", addr, err.Error())
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// isTrusted 客户端是否可信
+func (gw *Gateway) isTrusted(remoteAddr string) bool {
+	return containsAddr(gw.trusted, remoteAddr)
+}
+
+// isAdmin 客户端是否可以访问管理接口，本机总是允许
+func (gw *Gateway) isAdmin(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	return containsAddr(gw.admins, remoteAddr)
+}
+
+// containsAddr 客户端地址是否在网段中
+func containsAddr(nets []*net.IPNet, remoteAddr string) bool {
+	if len(nets) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeResult 写回转发的结果，返回http状态码
+// 服务返回错误时忽略响应中的状态码和Header
+func writeResult(w http.ResponseWriter, r *http.Request, resp *protocol.Proto, err error) int {
+	var status int
+	if err != nil {
+		log.Warn(err.Error())
+		if err == frame.ErrTimeout {
+			status = http.StatusGatewayTimeout
+			http.Error(w, errGatewayTimeout, status)
+		} else {
+			status = http.StatusInternalServerError
+			http.Error(w, errInternalError, status)
+		}
+	} else if len(resp.GetErr()) > 0 {
+		status = respErrStatus(resp.GetErr())
+		http.Error(w, string(resp.Err), status)
+	} else if len(resp.GetBody()) > 0 || resp.GetStatus() != 0 {
+		status = writeResponse(w, resp, r.Method == http.MethodHead)
+	} else {
+		status = http.StatusInternalServerError
+		http.Error(w, errInternalError, status)
+	}
+	return status
+}
+
+// writeResponse 按服务设置的状态码和Header写回响应，返回http状态码
+// HEAD请求只写回Header，Content-Length为响应体的长度
+func writeResponse(w http.ResponseWriter, resp *protocol.Proto, head bool) int {
+	h := w.Header()
+	for k, vs := range resp.HTTPHeader() {
+		h[k] = vs
+	}
+	status := int(resp.GetStatus())
+	if status == 0 {
+		status = http.StatusOK
+	} else if status < http.StatusOK || status > 599 {
+		log.Warnf("invalid response status %d,uri=%s method=%s", status, resp.GetServeURI(), resp.GetServeMethod())
+		status = http.StatusInternalServerError
+	}
+	if head {
+		h.Set(protocol.HeaderContentLength, strconv.Itoa(len(resp.GetBody())))
+	}
+	w.WriteHeader(status)
+	if !head {
+		w.Write(resp.GetBody())
+	}
+	return status
+}
+
+// respErrStatus 服务返回错误时的http状态码，错误码为4xx,5xx时使用错误码，否则为500
+func respErrStatus(errInfo []byte) int {
+	var ei protocol.ErrInfo
+	if err := json.Unmarshal(errInfo, &ei); err == nil &&
+		ei.ErrCode >= http.StatusBadRequest && ei.ErrCode < 600 {
+		return ei.ErrCode
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,149 @@
+package serve
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kwins/iceberg/frame/protocol"
+	gcfg "github.com/kwins/iceberg/gateway/config"
+)
+
+func multipartRequest(t *testing.T, content []byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "kwins")
+	fw, err := mw.CreateFormFile("avatar", "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	mw.Close()
+	r := httptest.NewRequest("POST", "/services/v1/hello/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestResolveMultipart(t *testing.T) {
+	task, err := resolveRequest(multipartRequest(t, []byte("png data")), gcfg.UploadCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Form["name"] != "kwins" || len(task.Files) != 1 {
+		t.Fatalf("form or files lost,%v", task)
+	}
+	f := task.Files[0]
+	if f.Field != "avatar" || f.Filename != "a.png" || string(f.Content) != "png data" || f.Size != 8 {
+		t.Errorf("file fail,%v", f)
+	}
+
+	if _, err := resolveRequest(multipartRequest(t, make([]byte, 2<<20)), gcfg.UploadCfg{MaxFileSize: 1}); err != errBodyTooLarge {
+		t.Errorf("file over limit should fail,%v", err)
+	}
+	// 没有Content-Length时读取超过限制失败
+	r := multipartRequest(t, make([]byte, 2<<20))
+	r.ContentLength = -1
+	if _, err := resolveRequest(r, gcfg.UploadCfg{MaxRequestSize: 1}); err != errBodyTooLarge {
+		t.Errorf("request over limit should fail,%v", err)
+	}
+}
+
+func TestWriteResponse(t *testing.T) {
+	var resp protocol.Proto
+	resp.SetHeader("Content-Type", "text/plain")
+	resp.AddHeader("Set-Cookie", "a=1")
+	resp.AddHeader("set-cookie", "b=2")
+	resp.Status = http.StatusCreated
+	resp.Body = []byte("created")
+	w := httptest.NewRecorder()
+	if status := writeResponse(w, &resp, false); status != http.StatusCreated || w.Code != http.StatusCreated {
+		t.Errorf("status fail,%d", w.Code)
+	}
+	if len(w.Header()["Set-Cookie"]) != 2 || w.Header().Get("Content-Type") != "text/plain" || w.Body.String() != "created" {
+		t.Errorf("header or body fail,%v %s", w.Header(), w.Body.String())
+	}
+
+	// HEAD去掉响应体
+	w = httptest.NewRecorder()
+	writeResponse(w, &resp, true)
+	if w.Body.Len() != 0 || w.Header().Get("Content-Length") != "7" {
+		t.Errorf("head response fail,%v %q", w.Header(), w.Body.String())
+	}
+
+	resp.Status = 42
+	if status := writeResponse(httptest.NewRecorder(), &resp, false); status != http.StatusInternalServerError {
+		t.Errorf("invalid status should be 500,got %d", status)
+	}
+}
+
+func TestWriteResultError(t *testing.T) {
+	// 设置了状态码后返回错误
+	var resp protocol.Proto
+	resp.Status = http.StatusFound
+	resp.SetHeader("Location", "/login")
+	resp.Err = []byte(`{"errcode":409,"errmsg":"conflict"}`)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "/services/v1/hello/update", nil)
+	if status := writeResult(w, r, &resp, nil); status != http.StatusConflict || w.Code != http.StatusConflict {
+		t.Errorf("error should win over status,got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "" || !strings.Contains(w.Body.String(), "conflict") {
+		t.Errorf("error response fail,%v %s", w.Header(), w.Body.String())
+	}
+}
+
+func TestResolveMethod(t *testing.T) {
+	for method, want := range map[string]protocol.RestfulMethod{
+		"PATCH":   protocol.RestfulMethod_PATCH,
+		"HEAD":    protocol.RestfulMethod_HEAD,
+		"OPTIONS": protocol.RestfulMethod_OPTIONS,
+		"TRACE":   protocol.RestfulMethod_METHODNULL,
+	} {
+		task, err := resolveRequest(httptest.NewRequest(method, "/services/v1/hello/sayhello", nil), gcfg.UploadCfg{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Method != want {
+			t.Errorf("%s want %s,got %s", method, want, task.Method)
+		}
+	}
+}
+
+func TestResolveMultiValues(t *testing.T) {
+	r := httptest.NewRequest("GET", "/services/v1/hello/list?id=1&id=2&name=kwins", nil)
+	r.Header.Add("X-Tag", "a")
+	r.Header.Add("X-Tag", "b")
+	task, err := resolveRequest(r, gcfg.UploadCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单值map保留第一个值，兼容旧版本
+	if task.Form["id"] != "1" || task.Header["X-Tag"] != "a" || len(task.FormValues) != 1 {
+		t.Errorf("single values fail,%v %v", task.Form, task.Header)
+	}
+	if ids := task.URLValues()["id"]; len(ids) != 2 || ids[1] != "2" {
+		t.Errorf("form values fail,%v", ids)
+	}
+	if tags := task.HTTPHeader()["X-Tag"]; len(tags) != 2 || tags[1] != "b" {
+		t.Errorf("header values fail,%v", tags)
+	}
+}
+
+func TestStripInternal(t *testing.T) {
+	r := httptest.NewRequest("GET", "/services/v1/hello/sayhi", nil)
+	r.Header.Set("X-Iceberg-Debug", "1")
+	r.Header.Set("X-Iceberg-Timeout", "86400000")
+	r.Header.Set("X-Iceberg-Baggage-User", "admin")
+	r.Header.Set("Accept-Language", "zh")
+	task, err := resolveRequest(r, gcfg.UploadCfg{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stripInternal(task)
+	if h := task.HTTPHeader(); len(h) != 1 || h.Get("Accept-Language") != "zh" {
+		t.Errorf("strip internal header fail,%v", h)
+	}
+}